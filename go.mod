@@ -1,3 +1,3 @@
 module github.com/h-dav/itertools
 
-go 1.18
+go 1.21
@@ -1,23 +1,141 @@
 package itertools
 
 import (
+	"cmp"
+	"container/heap"
+	"container/list"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Iterator chan interface{}
 
+// Number is satisfied by every built in integer and float type
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+var (
+	// ErrUnequalLengths is sent when parameters that must line up have differing lengths
+	ErrUnequalLengths = errors.New("all parameters must be of the same length")
+	// ErrInvalidOperator is sent when Accumulate is given an operator it does not know
+	ErrInvalidOperator = errors.New("not valid operator")
+	// ErrInvalidSize is sent when a size, window or count parameter is not greater than zero
+	ErrInvalidSize = errors.New("size must be greater than zero")
+	// ErrOverflow is sent when an integer result does not fit in its type
+	ErrOverflow = errors.New("integer overflow")
+	// ErrFieldCount is sent when the number of columns does not match the number of exported fields of a struct
+	ErrFieldCount = errors.New("number of columns does not match the number of exported fields")
+	// ErrDivideByZero is returned when a division has a zero divisor
+	ErrDivideByZero = errors.New("division by zero")
+	// ErrOutOfRange is sent when a parameter falls outside the range of values it may take
+	ErrOutOfRange = errors.New("parameter out of range")
+	// ErrTimeout is sent when an Iterator takes too long to produce its next element
+	ErrTimeout = errors.New("timed out waiting for the next element")
+	// ErrUnexpectedType is sent when an Iterator yields an element of a type the stage cannot handle
+	ErrUnexpectedType = errors.New("unexpected element type")
+	// ErrUnsorted is sent when input that must be in ascending order is not
+	ErrUnsorted = errors.New("input is not in ascending order")
+	// ErrMixedTypes is returned when elements that must be compared are of differing types
+	ErrMixedTypes = errors.New("elements are not all of the same type")
+	// ErrUnorderedType is returned when elements that must be compared are not numbers or strings
+	ErrUnorderedType = errors.New("elements are not of an ordered type")
+)
+
+// stops holds the stop signal of every running Iterator created by produce
+var (
+	stopsMu sync.Mutex
+	stops   = make(map[Iterator]chan struct{})
+)
+
+// produce runs fn in a goroutine that feeds the returned Iterator, send reports false once Stop is called and fn should return
+func produce(fn func(send func(any) bool)) Iterator {
+	ch, send, finish := stoppable()
+	go func() {
+		defer finish()
+		fn(send)
+	}()
+	return ch
+}
+
+// stoppable returns an Iterator that Stop works on, send blocks until the value is received and reports false once Stop
+// has been called, finish must be called once nothing more will be sent
+func stoppable() (ch Iterator, send func(any) bool, finish func()) {
+	ch = make(Iterator)
+	done := make(chan struct{})
+	stopsMu.Lock()
+	stops[ch] = done
+	stopsMu.Unlock()
+	send = func(value any) bool {
+		select {
+		case <-done:
+			return false
+		default:
+		}
+		select {
+		case ch <- value:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	finish = func() {
+		stopsMu.Lock()
+		delete(stops, ch)
+		stopsMu.Unlock()
+		close(ch)
+	}
+	return ch, send, finish
+}
+
+// Stop tells the producer behind ch to stop sending and close ch, it does nothing for an Iterator not made by this
+// package or whose producer has already finished
+func Stop(ch Iterator) {
+	stopsMu.Lock()
+	defer stopsMu.Unlock()
+	if done, ok := stops[ch]; ok {
+		close(done)
+		delete(stops, ch)
+	}
+}
+
+// Take returns an Iterator of the first n items of ch, stopping ch afterwards
+func Take(ch Iterator, n int) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		for i := 0; i < n; i++ {
+			value, ok := <-ch
+			if !ok || !send(value) {
+				return
+			}
+		}
+	})
+}
+
 // Iter returns an Iterator for the iterables parameter
 func Iter[T any](iterables []T) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
+	return produce(func(send func(any) bool) {
 		for _, value := range iterables {
-			ch <- value
+			if !send(value) {
+				return
+			}
 		}
-	}()
-	return
+	})
 }
 
 // Next goes to the next item within an Iterator
@@ -25,6 +143,86 @@ func Next(ch Iterator) any {
 	return <-ch
 }
 
+// NextTimeout goes to the next item within an Iterator, ok is false if ch is closed and err is ErrTimeout if nothing
+// arrives within d, in which case no item is taken from ch
+func NextTimeout(ch Iterator, d time.Duration) (value any, ok bool, err error) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case value, ok = <-ch:
+		return value, ok, nil
+	case <-timer.C:
+		return nil, false, ErrTimeout
+	}
+}
+
+// Collect drains ch into a slice, elements that are not a T are skipped
+func Collect[T any](ch Iterator) []T {
+	return CollectCap[T](ch, 0)
+}
+
+// CollectCap drains ch into a slice allocated up front to hold sizeHint elements, elements that are not a T are skipped
+func CollectCap[T any](ch Iterator, sizeHint int) []T {
+	if sizeHint < 0 {
+		sizeHint = 0
+	}
+	result := make([]T, 0, sizeHint)
+	for value := range ch {
+		if v, ok := value.(T); ok {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// CollectLimit drains up to limit elements of ch into a slice, reporting whether ch had more, in which case it reads one
+// element past the limit and stops ch, elements that are not a T are skipped
+func CollectLimit[T any](ch Iterator, limit int) ([]T, bool) {
+	result := make([]T, 0, max(min(limit, 1024), 0))
+	for value := range ch {
+		v, ok := value.(T)
+		if !ok {
+			continue
+		}
+		if len(result) >= limit {
+			Stop(ch)
+			return result, true
+		}
+		result = append(result, v)
+	}
+	return result, false
+}
+
+// CollectResult drains ch into a slice until the first error element, which is returned after stopping ch, an element
+// that is neither a T nor an error ends it the same way with ErrUnexpectedType
+func CollectResult[T any](ch Iterator) ([]T, error) {
+	defer Stop(ch)
+	var result []T
+	for value := range ch {
+		switch v := value.(type) {
+		case T:
+			result = append(result, v)
+		case error:
+			return result, v
+		default:
+			return result, fmt.Errorf("%w: %T is not a %T", ErrUnexpectedType, value, *new(T))
+		}
+	}
+	return result, nil
+}
+
+// CollectGroups drains ch into slices keyed by keyFn, keeping the order of each group, elements that are not a T are skipped
+func CollectGroups[T any, K comparable](ch Iterator, keyFn func(any) K) map[K][]any {
+	result := make(map[K][]any)
+	for value := range ch {
+		if _, ok := value.(T); ok {
+			key := keyFn(value)
+			result[key] = append(result[key], value)
+		}
+	}
+	return result
+}
+
 // Repeat returns an Iterator which contains value parameter, size parameter amount of times
 func Repeat(value any, size int) Iterator {
 	s := make([]any, size)
@@ -34,79 +232,142 @@ func Repeat(value any, size int) Iterator {
 	return Iter(s)
 }
 
-// Zip iterates over multiple data objects in sync
+// RepeatEach sends every element of iterable n times before the next, nothing is sent if n is not above zero
+func RepeatEach[T any](iterable []T, n int) Iterator {
+	return produce(func(send func(any) bool) {
+		for _, element := range iterable {
+			for i := 0; i < n; i++ {
+				if !send(element) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// RepeatForever returns an Iterator which yields value forever, call Stop to end it
+func RepeatForever(value any) Iterator {
+	return produce(func(send func(any) bool) {
+		for send(value) {
+		}
+	})
+}
+
+// Zip iterates over multiple data objects in sync, sending ErrUnequalLengths if some run out before the others
 func Zip[T any](iterables ...[]T) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
-		if ok := ensureSameLength(iterables); !ok {
-			ch <- "all parameters must be of the same length"
-			return
+	var fill T
+	return ZipWithMode(ZipStrict, fill, iterables...)
+}
+
+// ZipStrictAt returns Zip of iterables along with a function reporting the index at which the inputs stop being the
+// same length, which is where the Iterator sends ErrUnequalLengths, or -1 if they are all the same length
+func ZipStrictAt[T any](iterables ...[]T) (Iterator, func() int) {
+	divergence := -1
+	if !ensureSameLength(iterables) {
+		divergence = len(iterables[0])
+		for _, iterable := range iterables {
+			divergence = min(divergence, len(iterable))
 		}
-		var toSend []any
-		for index := range iterables[0] {
-			toSend = nil
+	}
+	return Zip(iterables...), func() int { return divergence }
+}
+
+// ZipMode selects how ZipWithMode handles inputs of differing lengths
+type ZipMode int
+
+const (
+	// ZipShortest stops once the shortest input runs out
+	ZipShortest ZipMode = iota
+	// ZipLongest continues until the longest input runs out, using the fill value for the others
+	ZipLongest
+	// ZipStrict sends ErrUnequalLengths if some inputs run out before the others
+	ZipStrict
+)
+
+// ZipWithMode sends a []any of the elements at each position of iterables, handling differing lengths according to mode
+func ZipWithMode[T any](mode ZipMode, fill T, iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
+		for index := 0; ; index++ {
+			exhausted := 0
 			for _, iterable := range iterables {
-				toSend = append(toSend, iterable[index])
+				if index >= len(iterable) {
+					exhausted++
+				}
+			}
+			if exhausted == len(iterables) {
+				return
+			}
+			if exhausted > 0 {
+				switch mode {
+				case ZipShortest:
+					return
+				case ZipStrict:
+					send(ErrUnequalLengths)
+					return
+				}
+			}
+			toSend := make([]any, len(iterables))
+			for i, iterable := range iterables {
+				toSend[i] = fill
+				if index < len(iterable) {
+					toSend[i] = iterable[index]
+				}
+			}
+			if !send(toSend) {
+				return
 			}
-			ch <- toSend
 		}
-	}()
-	return
+	})
 }
 
 // Chain allows for multiple arrays of the same type to be iterated over
-func Chain[T any](iterables ...[]T) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
+func Chain[T any](iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
 		for _, iterable := range iterables {
 			for index := range iterable {
-				ch <- iterable[index]
+				if !send(iterable[index]) {
+					return
+				}
 			}
 		}
-	}()
-	return
+	})
 }
 
-// Count counts up from a certain number in an increment
+// Count counts up from a certain number in an increment, call Stop to end it
 func Count[T float32 | float64 | int](start, step T) (ch Iterator) {
 	// consider changing step to uint
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
-		for {
-			ch <- start
+	return produce(func(send func(any) bool) {
+		for send(start) {
 			start = start + step
 		}
-	}()
-	return
+	})
 }
 
-// Cycle goes over a string seemingly forever
+// Cycle goes over a string seemingly forever, call Stop to end it
 func Cycle(iterable string) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
+	return produce(func(send func(any) bool) {
 		for {
 			letters := strings.SplitAfter(iterable, "")
 			for _, letter := range letters {
-				ch <- letter
+				if !send(letter) {
+					return
+				}
 			}
 		}
-	}()
-	return
+	})
 }
 
-func Accumulate(iterable []int, operator string, start int) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
+func Accumulate(iterable []int, operator string, start int) Iterator {
+	return produce(func(send func(any) bool) {
 		if start != 0 {
-			ch <- start
+			if !send(start) {
+				return
+			}
 		}
 		toSend := iterable[0]
-		ch <- toSend + start
+		if !send(toSend + start) {
+			return
+		}
 		for _, element := range iterable[1:] {
 			switch operator {
 			case "add", "":
@@ -116,66 +377,154 @@ func Accumulate(iterable []int, operator string, start int) (ch Iterator) {
 			case "power":
 				toSend = int(math.Pow(float64(toSend), float64(element)))
 			default:
-				ch <- "not valid operator"
+				send(ErrInvalidOperator)
+				return
+			}
+			if !send(toSend + start) {
 				return
 			}
-			ch <- toSend + start
 		}
-	}()
-	return
+	})
 }
 
-func Tee[T []int | string](iterable T, n int) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
+// AccumulateChecked behaves like Accumulate but sends ErrOverflow and stops instead of silently wrapping around, and
+// sends ErrInvalidOperator for a negative exponent with the power operator
+func AccumulateChecked(iterable []int, operator string, start int) Iterator {
+	return produce(func(send func(any) bool) {
+		if start != 0 {
+			if !send(start) {
+				return
+			}
+		}
+		if len(iterable) == 0 {
+			return
+		}
+		toSend := iterable[0]
+		for i, element := range iterable {
+			var err error
+			if i > 0 {
+				switch operator {
+				case "add", "":
+					toSend, err = checkedAdd(toSend, element)
+				case "multiply":
+					toSend, err = checkedMultiply(toSend, element)
+				case "power":
+					toSend, err = checkedPower(toSend, element)
+				default:
+					send(ErrInvalidOperator)
+					return
+				}
+			}
+			var result int
+			if err == nil {
+				result, err = checkedAdd(toSend, start)
+			}
+			if err != nil {
+				send(err)
+				return
+			}
+			if !send(result) {
+				return
+			}
+		}
+	})
+}
+
+func checkedAdd(a, b int) (int, error) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, ErrOverflow
+	}
+	return c, nil
+}
+
+func checkedMultiply(a, b int) (int, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return 0, ErrOverflow
+	}
+	return c, nil
+}
+
+// checkedPower raises base to exponent by repeated squaring, returning ErrOverflow as soon as a product overflows and
+// ErrInvalidOperator for a negative exponent, which has no integer result in general
+func checkedPower(base, exponent int) (int, error) {
+	if exponent < 0 {
+		return 0, fmt.Errorf("%w: negative exponent %d", ErrInvalidOperator, exponent)
+	}
+	result := 1
+	for {
+		var err error
+		if exponent&1 == 1 {
+			if result, err = checkedMultiply(result, base); err != nil {
+				return 0, err
+			}
+		}
+		if exponent >>= 1; exponent == 0 {
+			return result, nil
+		}
+		if base, err = checkedMultiply(base, base); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Tee splits iterable into consecutive chunks of n elements, or n bytes for a string, the last chunk may be shorter
+//
+// Deprecated: Tee does not copy an Iterator as its name suggests, use Segment for chunks or windows of a slice
+// and Clone or TeeBuffered to copy an Iterator.
+func Tee[T []int | string](iterable T, n int) Iterator {
+	return produce(func(send func(any) bool) {
 		switch reflect.TypeOf(iterable).Kind() {
 		case reflect.String:
 			value := reflect.ValueOf(iterable).String()
 			for len(value) != 0 {
 				if len(value) < n {
-					ch <- value
+					send(value)
+					return
+				}
+				if !send(value[0:n]) {
 					return
 				}
-				ch <- value[0:n]
 				value = value[n:]
 			}
 		case reflect.Array, reflect.Slice:
 			value := reflect.ValueOf(iterable)
 			for value.Len() != 0 {
 				if value.Len() < n {
-					ch <- value
+					send(value)
 					return
 				}
 				toSend := value.Slice(0, n)
 				value = value.Slice(n, value.Len())
-				ch <- toSend
+				if !send(toSend) {
+					return
+				}
 			}
 		}
-	}()
-	return
+	})
 }
 
-func Pairwise(iterable string) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
+func Pairwise(iterable string) Iterator {
+	return produce(func(send func(any) bool) {
 		innerCh := Tee(iterable, 2)
+		defer Stop(innerCh)
 		for value := range innerCh {
-			ch <- value
+			if !send(value) {
+				return
+			}
 		}
 
-	}()
-	return
+	})
 }
 
 // ensureSameLength ensures that all nested arrays are the same length
 func ensureSameLength[T any](nestedList [][]T) bool {
-	ch := Iter(nestedList)
-	first := Next(ch)
-	firstLength := reflect.ValueOf(first).Len()
-	for nested := range ch {
-		if reflect.ValueOf(nested).Len() != firstLength {
+	for _, nested := range nestedList {
+		if len(nested) != len(nestedList[0]) {
 			return false
 		}
 	}
@@ -183,15 +532,3366 @@ func ensureSameLength[T any](nestedList [][]T) bool {
 }
 
 // Compress filters elements from data returning only those that have a corresponding element in selector that is true
-func Compress[T any](data []T, selector []bool) (ch Iterator) {
-	ch = make(Iterator)
-	go func() {
-		defer close(ch)
+func Compress[T any](data []T, selector []bool) Iterator {
+	return produce(func(send func(any) bool) {
 		for i, d := range data {
 			if len(selector) > i && selector[i] {
-				ch <- d
+				if !send(d) {
+					return
+				}
 			}
 		}
-	}()
-	return
+	})
+}
+
+// Transpose swaps the rows and columns of matrix, returning ErrUnequalLengths if the rows are ragged
+func Transpose[T any](matrix [][]T) ([][]T, error) {
+	if len(matrix) == 0 {
+		return [][]T{}, nil
+	}
+	if ok := ensureSameLength(matrix); !ok {
+		return nil, ErrUnequalLengths
+	}
+	result := make([][]T, len(matrix[0]))
+	for column := range result {
+		result[column] = make([]T, len(matrix))
+		for row := range matrix {
+			result[column][row] = matrix[row][column]
+		}
+	}
+	return result, nil
+}
+
+// TransposeIter yields each column of matrix as a []T, sending ErrUnequalLengths if the rows are ragged
+func TransposeIter[T any](matrix [][]T) Iterator {
+	return produce(func(send func(any) bool) {
+		if len(matrix) == 0 {
+			return
+		}
+		if ok := ensureSameLength(matrix); !ok {
+			send(ErrUnequalLengths)
+			return
+		}
+		for column := range matrix[0] {
+			toSend := make([]T, len(matrix))
+			for row := range matrix {
+				toSend[row] = matrix[row][column]
+			}
+			if !send(toSend) {
+				return
+			}
+		}
+	})
+}
+
+// FilterMap applies fn to each element of iterable, sending the result only when fn reports true
+func FilterMap[T any, R any](iterable []T, fn func(T) (R, bool)) Iterator {
+	return produce(func(send func(any) bool) {
+		for _, element := range iterable {
+			if result, ok := fn(element); ok {
+				if !send(result) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// MaxIter drains ch and returns its largest element, ok is false if ch yielded nothing, at an element that cannot be
+// compared it stops ch and returns the error
+func MaxIter(ch Iterator) (max any, ok bool, err error) {
+	return extremeIter(ch, 1)
+}
+
+// MinIter is like MaxIter but returns the smallest element
+func MinIter(ch Iterator) (min any, ok bool, err error) {
+	return extremeIter(ch, -1)
+}
+
+// extremeIter returns the element of ch for which compareOrdered against every other element has the sign of want
+func extremeIter(ch Iterator, want int) (result any, ok bool, err error) {
+	for value := range ch {
+		if !ok {
+			result, ok = value, true
+			if _, err = compareOrdered(value, value); err != nil {
+				Stop(ch)
+				return nil, false, err
+			}
+			continue
+		}
+		var order int
+		if order, err = compareOrdered(value, result); err != nil {
+			Stop(ch)
+			return nil, false, err
+		}
+		if order == want {
+			result = value
+		}
+	}
+	return result, ok, nil
+}
+
+// compareOrdered returns -1, 0 or 1 depending on whether a is less than, equal to or greater than b
+func compareOrdered(a, b any) (int, error) {
+	first, second := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !first.IsValid() || !second.IsValid() {
+		return 0, ErrUnorderedType
+	}
+	if first.Type() != second.Type() {
+		return 0, ErrMixedTypes
+	}
+	switch first.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareValues(first.Int(), second.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return compareValues(first.Uint(), second.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return compareValues(first.Float(), second.Float()), nil
+	case reflect.String:
+		return compareValues(first.String(), second.String()), nil
+	default:
+		return 0, ErrUnorderedType
+	}
+}
+
+func compareValues[T int64 | uint64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// GroupReduce groups iterable by keyFn and folds each group into a single value, in input order, starting from initial
+func GroupReduce[T any, K comparable, R any](iterable []T, keyFn func(T) K, reduce func(acc R, x T) R, initial R) map[K]R {
+	result := make(map[K]R)
+	for _, element := range iterable {
+		key := keyFn(element)
+		acc, ok := result[key]
+		if !ok {
+			acc = initial
+		}
+		result[key] = reduce(acc, element)
+	}
+	return result
+}
+
+// Primes yields the prime numbers in order forever using an incremental sieve, call Stop to end it
+func Primes() Iterator {
+	return produce(func(send func(any) bool) {
+		// composites maps each upcoming composite number to the primes that divide it
+		composites := make(map[int][]int)
+		for n := 2; ; n++ {
+			if factors, ok := composites[n]; ok {
+				for _, prime := range factors {
+					composites[n+prime] = append(composites[n+prime], prime)
+				}
+				delete(composites, n)
+				continue
+			}
+			if !send(n) {
+				return
+			}
+			composites[n*n] = []int{n}
+		}
+	})
+}
+
+// Generate yields seed, then fn applied to the previous value, forever, call Stop to end it
+func Generate[T any](seed T, fn func(T) T) Iterator {
+	return produce(func(send func(any) bool) {
+		for send(seed) {
+			seed = fn(seed)
+		}
+	})
+}
+
+// GenerateState yields the outputs of repeatedly calling step, which is given the previous state and returns the next
+// state along with the value to yield, forever, call Stop to end it
+func GenerateState[S any, R any](initial S, step func(S) (S, R)) Iterator {
+	return produce(func(send func(any) bool) {
+		state := initial
+		for {
+			var output R
+			state, output = step(state)
+			if !send(output) {
+				return
+			}
+		}
+	})
+}
+
+// Fibonacci yields the Fibonacci sequence as ints forever, values after F(92) overflow int64 and wrap around, call Stop to end it
+func Fibonacci() Iterator {
+	return produce(func(send func(any) bool) {
+		a, b := 0, 1
+		for send(a) {
+			a, b = b, a+b
+		}
+	})
+}
+
+// FibonacciBig yields the Fibonacci sequence as a new *big.Int each time forever, call Stop to end it
+func FibonacciBig() Iterator {
+	return produce(func(send func(any) bool) {
+		a, b := big.NewInt(0), big.NewInt(1)
+		for send(new(big.Int).Set(a)) {
+			a.Add(a, b)
+			a, b = b, a
+		}
+	})
+}
+
+// MovingAverage yields the mean of each full window of the given size as a float64, windows shorter than size are skipped
+func MovingAverage[T Number](iterable []T, window int) Iterator {
+	return produce(func(send func(any) bool) {
+		if window <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		var sum float64
+		for i, element := range iterable {
+			sum += float64(element)
+			if i >= window {
+				sum -= float64(iterable[i-window])
+			}
+			if i >= window-1 {
+				if !send(sum / float64(window)) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Mask is the inverse of Compress, for each selector it sends the next element of data when true and fill when false,
+// stopping once selector is exhausted or a true selector has no data left
+func Mask[T any](data []T, selector []bool, fill T) Iterator {
+	return produce(func(send func(any) bool) {
+		next := 0
+		for _, selected := range selector {
+			if !selected {
+				if !send(fill) {
+					return
+				}
+				continue
+			}
+			if next >= len(data) {
+				return
+			}
+			if !send(data[next]) {
+				return
+			}
+			next++
+		}
+	})
+}
+
+// Timeout forwards the elements of ch, sending ErrTimeout and stopping ch if no element arrives within d of the last
+func Timeout(ch Iterator, d time.Duration) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		for {
+			select {
+			case value, ok := <-ch:
+				if !ok || !send(value) {
+					return
+				}
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(d)
+			case <-timer.C:
+				send(ErrTimeout)
+				return
+			}
+		}
+	})
+}
+
+// Equal drains a and b, reporting whether they yielded the same elements, compared with reflect.DeepEqual, in the same order
+func Equal(a, b Iterator) bool {
+	return EqualFunc(a, b, reflect.DeepEqual)
+}
+
+// EqualFunc drains a and b, reporting whether they yielded the same number of elements and eq holds for each pair
+func EqualFunc(a, b Iterator, eq func(x, y any) bool) bool {
+	equal := true
+	for {
+		x, okA := <-a
+		y, okB := <-b
+		if !okA || !okB {
+			equal = equal && okA == okB
+			break
+		}
+		equal = equal && eq(x, y)
+	}
+	for range a {
+	}
+	for range b {
+	}
+	return equal
+}
+
+// MapEntry is a single key and value pair from a map
+type MapEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+func (e MapEntry[K, V]) entryKey() any   { return e.Key }
+func (e MapEntry[K, V]) entryValue() any { return e.Value }
+
+// entry is satisfied by every MapEntry regardless of its type parameters
+type entry interface {
+	entryKey() any
+	entryValue() any
+}
+
+// IterMap yields a MapEntry for every key and value in m, in Go's unspecified map order
+func IterMap[K comparable, V any](m map[K]V) Iterator {
+	return produce(func(send func(any) bool) {
+		for key, value := range m {
+			if !send(MapEntry[K, V]{Key: key, Value: value}) {
+				return
+			}
+		}
+	})
+}
+
+// Keys yields the Key of every MapEntry in ch, sending ErrUnexpectedType for any other element
+func Keys(ch Iterator) Iterator {
+	return projectEntries(ch, entry.entryKey)
+}
+
+// Values yields the Value of every MapEntry in ch, sending ErrUnexpectedType for any other element
+func Values(ch Iterator) Iterator {
+	return projectEntries(ch, entry.entryValue)
+}
+
+func projectEntries(ch Iterator, project func(entry) any) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		for value := range ch {
+			e, ok := value.(entry)
+			if !ok {
+				send(fmt.Errorf("%w: %T is not a MapEntry", ErrUnexpectedType, value))
+				return
+			}
+			if !send(project(e)) {
+				return
+			}
+		}
+	})
+}
+
+// RepeatSlice yields every element of iterable, times times over, forever when times is negative, call Stop to end it
+func RepeatSlice[T any](iterable []T, times int) Iterator {
+	return produce(func(send func(any) bool) {
+		if len(iterable) == 0 {
+			return
+		}
+		for i := 0; times < 0 || i < times; i++ {
+			for _, value := range iterable {
+				if !send(value) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Iterate yields seed and then next applied to the previous value, ending after the first value for which stop returns true
+func Iterate[T any](seed T, next func(T) T, stop func(T) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		for send(seed) && !stop(seed) {
+			seed = next(seed)
+		}
+	})
+}
+
+// Unique returns the first occurrence of each value in iterable, in the order they first appear
+func Unique[T comparable](iterable []T) []T {
+	seen := make(map[T]bool)
+	result := []T{}
+	for _, element := range iterable {
+		if !seen[element] {
+			seen[element] = true
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// UniqueLast returns the last occurrence of each value in iterable, in the order those last occurrences appear
+func UniqueLast[T comparable](iterable []T) []T {
+	last := make(map[T]int)
+	for i, element := range iterable {
+		last[element] = i
+	}
+	result := []T{}
+	for i, element := range iterable {
+		if last[element] == i {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// Bucket distributes iterable into nBuckets slices by bucketFn, indices outside [0, nBuckets) are clamped to the nearest bucket
+func Bucket[T any](iterable []T, bucketFn func(T) int, nBuckets int) [][]T {
+	if nBuckets <= 0 {
+		return [][]T{}
+	}
+	buckets := make([][]T, nBuckets)
+	for _, element := range iterable {
+		index := bucketFn(element)
+		if index < 0 {
+			index = 0
+		} else if index >= nBuckets {
+			index = nBuckets - 1
+		}
+		buckets[index] = append(buckets[index], element)
+	}
+	return buckets
+}
+
+// Interleave sends one element from each of iterables in turn, stopping as soon as any of them is exhausted
+func Interleave[T any](iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
+		for index := 0; index < shortestLength(iterables); index++ {
+			for _, iterable := range iterables {
+				if !send(iterable[index]) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// InterleaveLongest sends one element from each of iterables in turn, skipping those that are exhausted until all are
+func InterleaveLongest[T any](iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
+		for index, sent := 0, true; sent; index++ {
+			sent = false
+			for _, iterable := range iterables {
+				if index < len(iterable) {
+					if !send(iterable[index]) {
+						return
+					}
+					sent = true
+				}
+			}
+		}
+	})
+}
+
+// StatefulMap threads state through fn for each element of iterable, sending fn's result whenever it reports true
+func StatefulMap[T any, S any, R any](iterable []T, initial S, fn func(state S, x T) (S, R, bool)) Iterator {
+	return produce(func(send func(any) bool) {
+		state := initial
+		for _, element := range iterable {
+			var result R
+			var emit bool
+			state, result, emit = fn(state, element)
+			if emit {
+				if !send(result) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Broadcast sends every element of ch to each of the n returned Iterators in lockstep, without buffering,
+// every consumer must keep reading or be stopped with Stop, otherwise the others and ch block forever,
+// ch is stopped once every Iterator is
+func Broadcast(ch Iterator, n int) []Iterator {
+	if n <= 0 {
+		return []Iterator{}
+	}
+	outputs := make([]Iterator, n)
+	sends := make([]func(any) bool, n)
+	finishes := make([]func(), n)
+	for i := range outputs {
+		outputs[i], sends[i], finishes[i] = stoppable()
+	}
+	go func() {
+		defer func() {
+			for _, finish := range finishes {
+				finish()
+			}
+		}()
+		defer Stop(ch)
+		live := n
+		for value := range ch {
+			for i, send := range sends {
+				if send != nil && !send(value) {
+					sends[i] = nil
+					live--
+				}
+			}
+			if live == 0 {
+				return
+			}
+		}
+	}()
+	return outputs
+}
+
+// Coalesce folds consecutive elements of iterable together with merge, sending the accumulator and starting
+// again from the current element whenever merge reports false
+func Coalesce[T any](iterable []T, merge func(acc, x T) (T, bool)) Iterator {
+	return produce(func(send func(any) bool) {
+		if len(iterable) == 0 {
+			return
+		}
+		acc := iterable[0]
+		for _, element := range iterable[1:] {
+			merged, ok := merge(acc, element)
+			if ok {
+				acc = merged
+				continue
+			}
+			if !send(acc) {
+				return
+			}
+			acc = element
+		}
+		if !send(acc) {
+			return
+		}
+	})
+}
+
+// Histogram counts iterable into bins equal width bins spanning its smallest to largest value, returning the counts and
+// the bins+1 edges, if every value is equal they are all counted in the first bin
+func Histogram[T Number](iterable []T, bins int) ([]int, []T) {
+	if len(iterable) == 0 || bins <= 0 {
+		return []int{}, []T{}
+	}
+	low, high := iterable[0], iterable[0]
+	for _, element := range iterable[1:] {
+		if element < low {
+			low = element
+		}
+		if element > high {
+			high = element
+		}
+	}
+	counts := make([]int, bins)
+	edges := make([]T, bins+1)
+	width := (float64(high) - float64(low)) / float64(bins)
+	for i := range edges {
+		edges[i] = T(float64(low) + width*float64(i))
+	}
+	edges[bins] = high
+	for _, element := range iterable {
+		index := 0
+		if width > 0 {
+			index = int((float64(element) - float64(low)) / width)
+		}
+		if index >= bins {
+			index = bins - 1
+		}
+		counts[index]++
+	}
+	return counts, edges
+}
+
+// Concat sends the elements of each part in order, where every part is either a []T or an Iterator,
+// sending ErrUnexpectedType and stopping at any other part, every Iterator part is stopped once Concat ends
+func Concat[T any](parts ...any) Iterator {
+	return produce(func(send func(any) bool) {
+		defer func() {
+			for _, part := range parts {
+				if p, ok := part.(Iterator); ok {
+					Stop(p)
+				}
+			}
+		}()
+		for i, part := range parts {
+			switch p := part.(type) {
+			case []T:
+				for _, value := range p {
+					if !send(value) {
+						return
+					}
+				}
+			case Iterator:
+				for value := range p {
+					if !send(value) {
+						return
+					}
+				}
+			default:
+				send(fmt.Errorf("%w: part %d is a %T", ErrUnexpectedType, i, part))
+				return
+			}
+		}
+	})
+}
+
+// FoldLeft combines the elements of iterable from first to last with fn, starting from initial
+func FoldLeft[T any, R any](iterable []T, fn func(acc R, x T) R, initial R) R {
+	acc := initial
+	for _, element := range iterable {
+		acc = fn(acc, element)
+	}
+	return acc
+}
+
+// FoldRight combines the elements of iterable from last to first with fn, starting from initial
+func FoldRight[T any, R any](iterable []T, fn func(x T, acc R) R, initial R) R {
+	acc := initial
+	for i := len(iterable) - 1; i >= 0; i-- {
+		acc = fn(iterable[i], acc)
+	}
+	return acc
+}
+
+// ZipWith sends fn applied to each pair of elements from a and b, stopping at the end of the shorter one
+func ZipWith[A any, B any, R any](a []A, b []B, fn func(A, B) R) Iterator {
+	return produce(func(send func(any) bool) {
+		for index := 0; index < len(a) && index < len(b); index++ {
+			if !send(fn(a[index], b[index])) {
+				return
+			}
+		}
+	})
+}
+
+// ZipWithIter sends fn applied to each pair of elements read in step from a and b, stopping at the end of the shorter
+// one and then stopping both iterators
+func ZipWithIter(a, b Iterator, fn func(x, y any) any) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(a)
+		defer Stop(b)
+		for {
+			x, ok := <-a
+			if !ok {
+				return
+			}
+			y, ok := <-b
+			if !ok || !send(fn(x, y)) {
+				return
+			}
+		}
+	})
+}
+
+// Difference sends the elements of a that are not present in b, in the order of a
+func Difference[T comparable](a, b []T) Iterator {
+	return filterByPresence(a, b, false)
+}
+
+// Intersection sends the elements of a that are also present in b, in the order of a
+func Intersection[T comparable](a, b []T) Iterator {
+	return filterByPresence(a, b, true)
+}
+
+func filterByPresence[T comparable](a, b []T, present bool) Iterator {
+	return produce(func(send func(any) bool) {
+		inB := make(map[T]bool, len(b))
+		for _, element := range b {
+			inB[element] = true
+		}
+		for _, element := range a {
+			if inB[element] == present {
+				if !send(element) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Union sends every distinct element of a and then b, in the order each is first seen
+func Union[T comparable](a, b []T) Iterator {
+	return produce(func(send func(any) bool) {
+		seen := make(map[T]bool)
+		for _, iterable := range [][]T{a, b} {
+			for _, element := range iterable {
+				if !seen[element] {
+					seen[element] = true
+					if !send(element) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// SortedUnion merges a and b, which must each be sorted ascending, into one ascending stream without duplicates in O(n+m)
+func SortedUnion[T cmp.Ordered](a, b []T) Iterator {
+	return produce(func(send func(any) bool) {
+		var last T
+		started := false
+		emit := func(element T) bool {
+			if started && element == last {
+				return true
+			}
+			last, started = element, true
+			return send(element)
+		}
+		i, j := 0, 0
+		for i < len(a) && j < len(b) {
+			if b[j] < a[i] {
+				if !emit(b[j]) {
+					return
+				}
+				j++
+			} else {
+				if !emit(a[i]) {
+					return
+				}
+				i++
+			}
+		}
+		for ; i < len(a); i++ {
+			if !emit(a[i]) {
+				return
+			}
+		}
+		for ; j < len(b); j++ {
+			if !emit(b[j]) {
+				return
+			}
+		}
+	})
+}
+
+// SampleEvery sends the first and then every nth element seen for each key, as computed by keyFn
+func SampleEvery[T any, K comparable](iterable []T, keyFn func(T) K, n int) Iterator {
+	return produce(func(send func(any) bool) {
+		if n <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		counts := make(map[K]int)
+		for _, element := range iterable {
+			key := keyFn(element)
+			if counts[key]%n == 0 {
+				if !send(element) {
+					return
+				}
+			}
+			counts[key]++
+		}
+	})
+}
+
+// RollingFold sends fn applied to each full window of the given size, fn is given its own copy of the window so may keep it
+func RollingFold[T any, R any](iterable []T, window int, fn func([]T) R) Iterator {
+	return produce(func(send func(any) bool) {
+		if window <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		for start := 0; start+window <= len(iterable); start++ {
+			current := make([]T, window)
+			copy(current, iterable[start:start+window])
+			if !send(fn(current)) {
+				return
+			}
+		}
+	})
+}
+
+// SkipNil forwards every element of ch that is not nil, including typed nils such as a nil pointer stored in an any
+func SkipNil(ch Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		for value := range ch {
+			if isNil(value) {
+				continue
+			}
+			if !send(value) {
+				return
+			}
+		}
+	})
+}
+
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice, reflect.UnsafePointer:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
+
+// Pipeline chains stages over a source Iterator, nothing runs until a terminal such as Collect, ForEach or Reduce is called,
+// an error returned by a stage or yielded by the source ends the pipeline and is returned by the terminal
+type Pipeline struct {
+	src    Iterator
+	stages []func(Iterator) Iterator
+}
+
+// NewPipeline returns a Pipeline reading from src
+func NewPipeline(src Iterator) Pipeline {
+	return Pipeline{src: src}
+}
+
+// Map adds a stage replacing every element with the result of fn, ending the pipeline if fn returns an error
+func (p Pipeline) Map(fn func(any) (any, error)) Pipeline {
+	return p.each(func(value any, send func(any) bool) bool {
+		result, err := fn(value)
+		if err != nil {
+			send(err)
+			return false
+		}
+		return send(result)
+	})
+}
+
+// Filter adds a stage keeping only the elements for which pred returns true
+func (p Pipeline) Filter(pred func(any) bool) Pipeline {
+	return p.each(func(value any, send func(any) bool) bool {
+		if pred(value) {
+			return send(value)
+		}
+		return true
+	})
+}
+
+// Take adds a stage keeping only the first n elements, stopping the earlier stages afterwards
+func (p Pipeline) Take(n int) Pipeline {
+	return p.pipe(func(ch Iterator) Iterator {
+		return Take(ch, n)
+	})
+}
+
+// Iter runs the pipeline and returns its output, errors are yielded as elements
+func (p Pipeline) Iter() Iterator {
+	ch := p.src
+	for _, stage := range p.stages {
+		ch = stage(ch)
+	}
+	return ch
+}
+
+// ForEach runs the pipeline calling fn for every element, returning the first error encountered
+func (p Pipeline) ForEach(fn func(any)) error {
+	ch := p.Iter()
+	for value := range ch {
+		if err, ok := value.(error); ok {
+			Stop(ch)
+			return err
+		}
+		fn(value)
+	}
+	return nil
+}
+
+// Collect runs the pipeline and returns every element, or the first error encountered
+func (p Pipeline) Collect() ([]any, error) {
+	result := []any{}
+	if err := p.ForEach(func(value any) { result = append(result, value) }); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Reduce runs the pipeline combining its elements with fn starting from initial, or returns the first error encountered
+func (p Pipeline) Reduce(fn func(acc, x any) any, initial any) (any, error) {
+	acc := initial
+	if err := p.ForEach(func(value any) { acc = fn(acc, value) }); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// pipe returns a copy of p with stage added, so that pipelines sharing a prefix do not interfere
+func (p Pipeline) pipe(stage func(Iterator) Iterator) Pipeline {
+	stages := make([]func(Iterator) Iterator, len(p.stages), len(p.stages)+1)
+	copy(stages, p.stages)
+	return Pipeline{src: p.src, stages: append(stages, stage)}
+}
+
+// each adds a stage calling fn for every element until it returns false, errors are forwarded and end the stage
+func (p Pipeline) each(fn func(value any, send func(any) bool) bool) Pipeline {
+	return p.pipe(func(ch Iterator) Iterator {
+		return produce(func(send func(any) bool) {
+			defer Stop(ch)
+			for value := range ch {
+				if err, ok := value.(error); ok {
+					send(err)
+					return
+				}
+				if !fn(value, send) {
+					return
+				}
+			}
+		})
+	})
+}
+
+// DedupBy sends the first element of every run of consecutive elements where eq holds between each neighbouring pair
+func DedupBy[T any](iterable []T, eq func(a, b T) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		for i, element := range iterable {
+			if i > 0 && eq(iterable[i-1], element) {
+				continue
+			}
+			if !send(element) {
+				return
+			}
+		}
+	})
+}
+
+// PrefixSums returns the running totals of iterable, the same length as iterable
+func PrefixSums[T Number](iterable []T) []T {
+	return prefixReduce(iterable, func(acc, x T) T { return acc + x })
+}
+
+// PrefixProducts returns the running products of iterable, the same length as iterable
+func PrefixProducts[T Number](iterable []T) []T {
+	return prefixReduce(iterable, func(acc, x T) T { return acc * x })
+}
+
+func prefixReduce[T Number](iterable []T, fn func(acc, x T) T) []T {
+	result := make([]T, len(iterable))
+	for i, element := range iterable {
+		if i == 0 {
+			result[i] = element
+			continue
+		}
+		result[i] = fn(result[i-1], element)
+	}
+	return result
+}
+
+// UpdateMap writes every MapEntry from entries into dst, with later entries winning for duplicate keys, it stops and returns
+// at the first element that is an error or not a MapEntry[K, V]
+func UpdateMap[K comparable, V any](dst map[K]V, entries Iterator) error {
+	defer Stop(entries)
+	for value := range entries {
+		switch e := value.(type) {
+		case MapEntry[K, V]:
+			dst[e.Key] = e.Value
+		case error:
+			return e
+		default:
+			return fmt.Errorf("%w: %T is not a MapEntry[%T, %T]", ErrUnexpectedType, value, *new(K), *new(V))
+		}
+	}
+	return nil
+}
+
+// Group is a key together with the elements that share it
+type Group[K any, T any] struct {
+	Key   K
+	Items []T
+}
+
+// GroupBySorted sends a Group for every distinct key in ascending key order, it copies and stably sorts iterable
+// by keyFn before grouping so, unlike streaming groupings, equal keys need not be adjacent in the input
+func GroupBySorted[T any, K cmp.Ordered](iterable []T, keyFn func(T) K) Iterator {
+	return produce(func(send func(any) bool) {
+		sorted := make([]MapEntry[K, T], len(iterable))
+		for i, element := range iterable {
+			sorted[i] = MapEntry[K, T]{Key: keyFn(element), Value: element}
+		}
+		slices.SortStableFunc(sorted, func(a, b MapEntry[K, T]) int { return cmp.Compare(a.Key, b.Key) })
+		for start := 0; start < len(sorted); {
+			group := Group[K, T]{Key: sorted[start].Key}
+			end := start
+			for ; end < len(sorted) && cmp.Compare(sorted[end].Key, group.Key) == 0; end++ {
+				group.Items = append(group.Items, sorted[end].Value)
+			}
+			if !send(group) {
+				return
+			}
+			start = end
+		}
+	})
+}
+
+// GroupAggSorted sends a MapEntry of every distinct key and agg applied to the elements sharing it, in ascending key order
+func GroupAggSorted[T any, K cmp.Ordered, R any](iterable []T, keyFn func(T) K, agg func([]T) R) Iterator {
+	return produce(func(send func(any) bool) {
+		groups := GroupBySorted(iterable, keyFn)
+		defer Stop(groups)
+		for value := range groups {
+			group := value.(Group[K, T])
+			if !send(MapEntry[K, R]{Key: group.Key, Value: agg(group.Items)}) {
+				return
+			}
+		}
+	})
+}
+
+// BatchWeighted sends the elements of iterable as []T batches whose total weight does not exceed maxWeight,
+// an element heavier than maxWeight on its own is sent as a batch by itself
+func BatchWeighted[T any](iterable []T, weightFn func(T) int, maxWeight int) Iterator {
+	return produce(func(send func(any) bool) {
+		var batch []T
+		weight := 0
+		for _, element := range iterable {
+			w := weightFn(element)
+			if len(batch) > 0 && weight+w > maxWeight {
+				if !send(batch) {
+					return
+				}
+				batch, weight = nil, 0
+			}
+			batch = append(batch, element)
+			weight += w
+		}
+		if len(batch) > 0 {
+			if !send(batch) {
+				return
+			}
+		}
+	})
+}
+
+// CycleN goes over iterable repeatedly, stopping after maxElements elements in total or at once if iterable is empty
+func CycleN[T any](iterable []T, maxElements int) Iterator {
+	return produce(func(send func(any) bool) {
+		if len(iterable) == 0 {
+			return
+		}
+		for i := 0; i < maxElements; i++ {
+			if !send(iterable[i%len(iterable)]) {
+				return
+			}
+		}
+	})
+}
+
+// SplitIter yields the substrings of s between each sep as it finds them, like strings.Split a trailing sep yields
+// a final empty string and an empty sep yields each rune as its own string, call Stop to end it early
+func SplitIter(s string, sep string) Iterator {
+	return produce(func(send func(any) bool) {
+		if sep == "" {
+			for _, r := range s {
+				if !send(string(r)) {
+					return
+				}
+			}
+			return
+		}
+		for {
+			index := strings.Index(s, sep)
+			if index < 0 {
+				send(s)
+				return
+			}
+			if !send(s[:index]) {
+				return
+			}
+			s = s[index+len(sep):]
+		}
+	})
+}
+
+// ZipCount sends []any{index, element} for each element of iterable, with indexes drawn from Count starting at start,
+// Count is stopped once iterable is exhausted
+func ZipCount[T any](iterable []T, start int) Iterator {
+	return produce(func(send func(any) bool) {
+		counter := Count(start, 1)
+		defer Stop(counter)
+		for _, element := range iterable {
+			if !send([]any{Next(counter), element}) {
+				return
+			}
+		}
+	})
+}
+
+// ProductIndices yields every []int index tuple of a shape with the given dims, with the last dimension advancing fastest
+func ProductIndices(dims ...int) Iterator {
+	return produce(func(send func(any) bool) {
+		if len(dims) == 0 {
+			return
+		}
+		for _, dim := range dims {
+			if dim <= 0 {
+				return
+			}
+		}
+		indices := make([]int, len(dims))
+		for {
+			if !send(append([]int(nil), indices...)) {
+				return
+			}
+			position := len(dims) - 1
+			for ; position >= 0; position-- {
+				indices[position]++
+				if indices[position] < dims[position] {
+					break
+				}
+				indices[position] = 0
+			}
+			if position < 0 {
+				return
+			}
+		}
+	})
+}
+
+// Product yields the cartesian product of iterables as []T tuples, in the same order as ProductIndices
+func Product[T any](iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
+		dims := make([]int, len(iterables))
+		for i, iterable := range iterables {
+			dims[i] = len(iterable)
+		}
+		indices := ProductIndices(dims...)
+		defer Stop(indices)
+		for value := range indices {
+			tuple := make([]T, len(iterables))
+			for i, index := range value.([]int) {
+				tuple[i] = iterables[i][index]
+			}
+			if !send(tuple) {
+				return
+			}
+		}
+	})
+}
+
+// ProductAny yields the cartesian product of iterables as []any tuples like Product, for inputs of differing element types
+func ProductAny(iterables ...[]any) Iterator {
+	return Product(iterables...)
+}
+
+// DropLast sends every element of iterable except the final n
+func DropLast[T any](iterable []T, n int) Iterator {
+	return DropLastIter(Iter(iterable), n)
+}
+
+// DropLastIter forwards every element of ch except the final n, holding n elements back until it knows more follow
+func DropLastIter(ch Iterator, n int) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		if n <= 0 {
+			for value := range ch {
+				if !send(value) {
+					return
+				}
+			}
+			return
+		}
+		buffer := make([]any, 0, n)
+		next := 0
+		for value := range ch {
+			if len(buffer) < n {
+				buffer = append(buffer, value)
+				continue
+			}
+			if !send(buffer[next]) {
+				return
+			}
+			buffer[next] = value
+			next = (next + 1) % n
+		}
+	})
+}
+
+// TakeLastWhile returns the longest suffix of iterable whose every element satisfies pred
+func TakeLastWhile[T any](iterable []T, pred func(T) bool) []T {
+	start := len(iterable)
+	for start > 0 && pred(iterable[start-1]) {
+		start--
+	}
+	return append([]T{}, iterable[start:]...)
+}
+
+// Clone returns two Iterators that each yield every remaining element of ch, buffering whatever one has not yet read
+func Clone(ch Iterator) (Iterator, Iterator) {
+	outputs := tee(ch, 2, 0)
+	return outputs[0], outputs[1]
+}
+
+// teeQueue holds the elements one output of tee has yet to send, stopped is set once that output has been stopped
+type teeQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   []any
+	done    bool
+	stopped bool
+}
+
+// tee copies every element of ch to n outputs with a queue each, when capacity is above zero reading from ch
+// blocks while any queue already holds capacity elements, ch is stopped once every output is
+func tee(ch Iterator, n, capacity int) []Iterator {
+	outputs := make([]Iterator, n)
+	queues := make([]*teeQueue, n)
+	for i := range outputs {
+		out, send, finish := stoppable()
+		outputs[i] = out
+		queues[i] = &teeQueue{}
+		queues[i].cond = sync.NewCond(&queues[i].mu)
+		go func(q *teeQueue) {
+			defer finish()
+			for {
+				q.mu.Lock()
+				for len(q.items) == 0 && !q.done {
+					q.cond.Wait()
+				}
+				if len(q.items) == 0 {
+					q.mu.Unlock()
+					return
+				}
+				value := q.items[0]
+				q.items[0] = nil
+				q.items = q.items[1:]
+				q.cond.Broadcast()
+				q.mu.Unlock()
+				if !send(value) {
+					q.mu.Lock()
+					q.stopped, q.items = true, nil
+					q.cond.Broadcast()
+					q.mu.Unlock()
+					return
+				}
+			}
+		}(queues[i])
+	}
+	go func() {
+		defer func() {
+			for _, q := range queues {
+				q.mu.Lock()
+				q.done = true
+				q.cond.Broadcast()
+				q.mu.Unlock()
+			}
+		}()
+		defer Stop(ch)
+		for value := range ch {
+			live := 0
+			for _, q := range queues {
+				q.mu.Lock()
+				for capacity > 0 && len(q.items) >= capacity && !q.stopped {
+					q.cond.Wait()
+				}
+				if !q.stopped {
+					q.items = append(q.items, value)
+					live++
+				}
+				q.cond.Broadcast()
+				q.mu.Unlock()
+			}
+			if live == 0 {
+				return
+			}
+		}
+	}()
+	return outputs
+}
+
+// Linspace yields n evenly spaced float64 values from start to stop inclusive, n of 1 yields only start and n below 1 yields nothing
+func Linspace(start, stop float64, n int) Iterator {
+	return produce(func(send func(any) bool) {
+		if n == 1 {
+			send(start)
+			return
+		}
+		step := (stop - start) / float64(n-1)
+		for i := 0; i < n; i++ {
+			if i == n-1 {
+				send(stop)
+				return
+			}
+			if !send(start + step*float64(i)) {
+				return
+			}
+		}
+	})
+}
+
+// Records sends a map from each header field to its value for every row, sending ErrUnequalLengths and stopping
+// at the first row whose width differs from header
+func Records(header []string, rows [][]string) Iterator {
+	return produce(func(send func(any) bool) {
+		for i, row := range rows {
+			if len(row) != len(header) {
+				send(fmt.Errorf("%w: row %d has %d fields, header has %d", ErrUnequalLengths, i, len(row), len(header)))
+				return
+			}
+			record := make(map[string]string, len(header))
+			for column, field := range header {
+				record[field] = row[column]
+			}
+			if !send(record) {
+				return
+			}
+		}
+	})
+}
+
+// GroupBy sends a Group for every run of consecutive elements of iterable that share the same key, like Python's groupby
+func GroupBy[T any, K comparable](iterable []T, keyFn func(T) K) Iterator {
+	return produce(func(send func(any) bool) {
+		var group Group[K, T]
+		for i, element := range iterable {
+			key := keyFn(element)
+			if i > 0 && key != group.Key {
+				if !send(group) {
+					return
+				}
+				group = Group[K, T]{}
+			}
+			group.Key = key
+			group.Items = append(group.Items, element)
+		}
+		if len(iterable) > 0 {
+			if !send(group) {
+				return
+			}
+		}
+	})
+}
+
+// Ungroup flattens a stream of Group[K, V] back into a MapEntry for each item, sending ErrUnexpectedType for any other element
+func Ungroup[K comparable, V any](groups Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(groups)
+		for value := range groups {
+			group, ok := value.(Group[K, V])
+			if !ok {
+				send(fmt.Errorf("%w: %T is not a Group[%T, %T]", ErrUnexpectedType, value, *new(K), *new(V)))
+				return
+			}
+			for _, item := range group.Items {
+				if !send(MapEntry[K, V]{Key: group.Key, Value: item}) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// DistinctCount sends the number of distinct values in each full sliding window of the given size, updating counts
+// as the window slides rather than recounting
+func DistinctCount[T comparable](iterable []T, window int) Iterator {
+	return produce(func(send func(any) bool) {
+		if window <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		counts := make(map[T]int)
+		for i, element := range iterable {
+			counts[element]++
+			if i >= window {
+				old := iterable[i-window]
+				if counts[old]--; counts[old] == 0 {
+					delete(counts, old)
+				}
+			}
+			if i >= window-1 {
+				if !send(len(counts)) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// ZipStruct sends an S for each row of columns, setting the exported fields of S in order from the columns and stopping
+// at the shortest column, it sends ErrFieldCount if the number of columns and fields differ
+func ZipStruct[S any](columns ...[]any) Iterator {
+	return produce(func(send func(any) bool) {
+		fields, err := exportedFields(reflect.TypeOf(*new(S)), len(columns))
+		if err != nil {
+			send(err)
+			return
+		}
+		for row := 0; row < shortestLength(columns); row++ {
+			var result S
+			if err := fillFields(reflect.ValueOf(&result).Elem(), fields, columns, row); err != nil {
+				send(err)
+				return
+			}
+			if !send(result) {
+				return
+			}
+		}
+	})
+}
+
+// exportedFields returns the indexes of the exported fields of struct type t, checking there are exactly want of them
+func exportedFields(t reflect.Type, want int) ([]int, error) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %v is not a struct", ErrUnexpectedType, t)
+	}
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			fields = append(fields, i)
+		}
+	}
+	if len(fields) != want {
+		return nil, fmt.Errorf("%w: %v has %d, got %d columns", ErrFieldCount, t, len(fields), want)
+	}
+	return fields, nil
+}
+
+// fillFields sets each of fields in target to the value at row in the matching column
+func fillFields(target reflect.Value, fields []int, columns [][]any, row int) error {
+	for column, field := range fields {
+		value := reflect.ValueOf(columns[column][row])
+		fieldValue := target.Field(field)
+		if !value.IsValid() {
+			continue
+		}
+		if !value.Type().AssignableTo(fieldValue.Type()) {
+			return fmt.Errorf("%w: cannot set field %s of type %v to %v", ErrUnexpectedType,
+				target.Type().Field(field).Name, fieldValue.Type(), value.Type())
+		}
+		fieldValue.Set(value)
+	}
+	return nil
+}
+
+func shortestLength[T any](iterables [][]T) int {
+	if len(iterables) == 0 {
+		return 0
+	}
+	shortest := len(iterables[0])
+	for _, iterable := range iterables[1:] {
+		if len(iterable) < shortest {
+			shortest = len(iterable)
+		}
+	}
+	return shortest
+}
+
+// IterJSONArray decodes the elements of a top level JSON array from r one at a time, sending any decoding error and stopping
+func IterJSONArray(r io.Reader) Iterator {
+	return produce(func(send func(any) bool) {
+		decoder := json.NewDecoder(r)
+		token, err := decoder.Token()
+		if err != nil {
+			send(err)
+			return
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			send(fmt.Errorf("%w: expected a JSON array, got %v", ErrUnexpectedType, token))
+			return
+		}
+		for decoder.More() {
+			var element any
+			if err := decoder.Decode(&element); err != nil {
+				send(err)
+				return
+			}
+			if !send(element) {
+				return
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			send(err)
+		}
+	})
+}
+
+// Pair holds two values of possibly differing types
+type Pair[A any, B any] struct {
+	First  A
+	Second B
+}
+
+// JoinBy sends a Pair for every element of a and element of b whose keys are equal, in the order of a then b,
+// so keys repeated on both sides yield every combination of their elements
+func JoinBy[A any, B any, K comparable](a []A, b []B, keyA func(A) K, keyB func(B) K) Iterator {
+	return produce(func(send func(any) bool) {
+		byKey := make(map[K][]B)
+		for _, element := range b {
+			key := keyB(element)
+			byKey[key] = append(byKey[key], element)
+		}
+		for _, left := range a {
+			for _, right := range byKey[keyA(left)] {
+				if !send(Pair[A, B]{First: left, Second: right}) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// CrossJoin sends a Pair for every element of as and element of bs for which pred is true, in the order of as then bs,
+// every combination is tested so it takes O(n*m) calls of pred
+func CrossJoin[A any, B any](as []A, bs []B, pred func(A, B) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		for _, left := range as {
+			for _, right := range bs {
+				if pred(left, right) {
+					if !send(Pair[A, B]{First: left, Second: right}) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+// TeeBuffered returns n Iterators that each yield every remaining element of ch, buffering at most about capacity elements
+// per Iterator before reading from ch blocks, if one Iterator stops being read the rest block once its buffer is full
+func TeeBuffered(ch Iterator, n, capacity int) []Iterator {
+	if n <= 0 {
+		return []Iterator{}
+	}
+	return tee(ch, n, capacity)
+}
+
+// RunningQuantile sends an estimate of the q quantile of the numbers seen so far after each element of ch, using the P²
+// algorithm so only five markers are kept rather than the whole stream, q must be strictly between 0 and 1
+func RunningQuantile(ch Iterator, q float64) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		if q <= 0 || q >= 1 {
+			send(fmt.Errorf("%w: quantile %v is not between 0 and 1", ErrOutOfRange, q))
+			return
+		}
+		var heights []float64
+		positions := []float64{1, 2, 3, 4, 5}
+		desired := []float64{1, 1 + 2*q, 1 + 4*q, 3 + 2*q, 5}
+		increments := []float64{0, q / 2, q, (1 + q) / 2, 1}
+		for value := range ch {
+			x, ok := toFloat(value)
+			if !ok {
+				send(fmt.Errorf("%w: %T is not a number", ErrUnexpectedType, value))
+				return
+			}
+			if len(heights) < 5 {
+				heights = append(heights, x)
+				slices.Sort(heights)
+				if !send(heights[int(math.Round(q*float64(len(heights)-1)))]) {
+					return
+				}
+				continue
+			}
+			var k int
+			switch {
+			case x < heights[0]:
+				heights[0], k = x, 0
+			case x >= heights[4]:
+				heights[4], k = x, 3
+			default:
+				for k = 0; x >= heights[k+1]; k++ {
+				}
+			}
+			for i := k + 1; i < 5; i++ {
+				positions[i]++
+			}
+			for i := range desired {
+				desired[i] += increments[i]
+			}
+			for i := 1; i < 4; i++ {
+				d := desired[i] - positions[i]
+				if (d >= 1 && positions[i+1]-positions[i] > 1) || (d <= -1 && positions[i-1]-positions[i] < -1) {
+					d = math.Copysign(1, d)
+					height := heights[i] + d/(positions[i+1]-positions[i-1])*
+						((positions[i]-positions[i-1]+d)*(heights[i+1]-heights[i])/(positions[i+1]-positions[i])+
+							(positions[i+1]-positions[i]-d)*(heights[i]-heights[i-1])/(positions[i]-positions[i-1]))
+					if height <= heights[i-1] || height >= heights[i+1] {
+						j := i + int(d)
+						height = heights[i] + d*(heights[j]-heights[i])/(positions[j]-positions[i])
+					}
+					heights[i] = height
+					positions[i] += d
+				}
+			}
+			if !send(heights[2]) {
+				return
+			}
+		}
+	})
+}
+
+// toFloat converts any integer or float value to a float64, reporting false for anything else
+func toFloat(value any) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// Pad sends iterable with fill added after it, or before it when atFront is true, until target elements have been sent,
+// iterable is sent unchanged if it already has target or more elements
+func Pad[T any](iterable []T, target int, fill T, atFront bool) Iterator {
+	return produce(func(send func(any) bool) {
+		padding := target - len(iterable)
+		if atFront {
+			for i := 0; i < padding; i++ {
+				if !send(fill) {
+					return
+				}
+			}
+		}
+		for _, element := range iterable {
+			if !send(element) {
+				return
+			}
+		}
+		if !atFront {
+			for i := 0; i < padding; i++ {
+				if !send(fill) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Triple holds three values of possibly differing types
+type Triple[A any, B any, C any] struct {
+	First  A
+	Second B
+	Third  C
+}
+
+// Zip2 sends a Pair for each position of a and b, stopping at the end of the shorter one
+func Zip2[A any, B any](a []A, b []B) Iterator {
+	return ZipWith(a, b, func(x A, y B) Pair[A, B] { return Pair[A, B]{First: x, Second: y} })
+}
+
+// IndexedPair is an element of EnumerateZip2, the elements of both inputs at Index
+type IndexedPair[A any, B any] struct {
+	Index int
+	A     A
+	B     B
+}
+
+// EnumerateZip2 sends an IndexedPair for each position of a and b, stopping at the end of the shorter one
+func EnumerateZip2[A any, B any](a []A, b []B) Iterator {
+	return produce(func(send func(any) bool) {
+		for index := 0; index < len(a) && index < len(b); index++ {
+			if !send(IndexedPair[A, B]{Index: index, A: a[index], B: b[index]}) {
+				return
+			}
+		}
+	})
+}
+
+// Zip3 sends a Triple for each position of a, b and c, stopping at the end of the shortest one,
+// more inputs can be zipped as []any with Zip
+func Zip3[A any, B any, C any](a []A, b []B, c []C) Iterator {
+	return produce(func(send func(any) bool) {
+		for index := 0; index < len(a) && index < len(b) && index < len(c); index++ {
+			if !send(Triple[A, B, C]{First: a[index], Second: b[index], Third: c[index]}) {
+				return
+			}
+		}
+	})
+}
+
+// Segment sends []T segments of size elements from iterable, consecutive chunks with the last one possibly shorter when
+// overlap is false, or every full sliding window when overlap is true
+func Segment[T any](iterable []T, size int, overlap bool) Iterator {
+	return produce(func(send func(any) bool) {
+		if size <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		step := size
+		if overlap {
+			step = 1
+		}
+		for start := 0; start < len(iterable); start += step {
+			end := start + size
+			if end > len(iterable) {
+				if overlap {
+					return
+				}
+				end = len(iterable)
+			}
+			if !send(append([]T{}, iterable[start:end]...)) {
+				return
+			}
+		}
+	})
+}
+
+// WindowView sends every full sliding window of size elements of iterable like Segment with overlap, but as a view of
+// iterable rather than a copy, to save an allocation per window.
+// WARNING: each window shares memory with iterable and with the windows around it, so writing to one changes the
+// others and the input, only use a window before receiving the next and copy it if it must be kept or changed
+func WindowView[T any](iterable []T, size int) Iterator {
+	return produce(func(send func(any) bool) {
+		if size <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		for end := size; end <= len(iterable); end++ {
+			if !send(iterable[end-size : end : end]) {
+				return
+			}
+		}
+	})
+}
+
+// CountDown counts down from start to stop, exclusive, subtracting step each time, step must be greater than zero
+func CountDown(start, stop, step int) Iterator {
+	return produce(func(send func(any) bool) {
+		if step <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		for ; start > stop; start -= step {
+			if !send(start) {
+				return
+			}
+		}
+	})
+}
+
+// ZipLongestIter sends a []any of the next element of each of iterators, using fill for those already exhausted,
+// until all of them are exhausted
+func ZipLongestIter(fill any, iterators ...Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer func() {
+			for _, iterator := range iterators {
+				Stop(iterator)
+			}
+		}()
+		done := make([]bool, len(iterators))
+		for {
+			toSend := make([]any, len(iterators))
+			live := 0
+			for i, iterator := range iterators {
+				toSend[i] = fill
+				if done[i] {
+					continue
+				}
+				value, ok := <-iterator
+				if !ok {
+					done[i] = true
+					continue
+				}
+				toSend[i] = value
+				live++
+			}
+			if live == 0 || !send(toSend) {
+				return
+			}
+		}
+	})
+}
+
+// SessionWindow sends iterable as []T sessions, starting a new one with cur whenever isBoundary(prev, cur) is true,
+// the final session is always sent
+func SessionWindow[T any](iterable []T, isBoundary func(prev, cur T) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		var session []T
+		for i, element := range iterable {
+			if i > 0 && isBoundary(iterable[i-1], element) {
+				if !send(session) {
+					return
+				}
+				session = nil
+			}
+			session = append(session, element)
+		}
+		if len(session) > 0 {
+			if !send(session) {
+				return
+			}
+		}
+	})
+}
+
+// ArgMax returns the index and value of the largest element of iterable, the first one on ties, ok is false if iterable is empty
+func ArgMax[T cmp.Ordered](iterable []T) (index int, value T, ok bool) {
+	return argExtreme(iterable, 1)
+}
+
+// ArgMin returns the index and value of the smallest element of iterable, the first one on ties, ok is false if iterable is empty
+func ArgMin[T cmp.Ordered](iterable []T) (index int, value T, ok bool) {
+	return argExtreme(iterable, -1)
+}
+
+func argExtreme[T cmp.Ordered](iterable []T, want int) (index int, value T, ok bool) {
+	if len(iterable) == 0 {
+		return -1, value, false
+	}
+	for i, element := range iterable {
+		if cmp.Compare(element, iterable[index]) == want {
+			index = i
+		}
+	}
+	return index, iterable[index], true
+}
+
+// Unzip is the inverse of Zip, it returns n Iterators where the ith yields position i of every []any tuple from ch,
+// buffering tuples that the other Iterators have yet to read, a tuple of the wrong length or type sends an error to each
+func Unzip(ch Iterator, n int) []Iterator {
+	if n <= 0 {
+		return []Iterator{}
+	}
+	copies := tee(ch, n, 0)
+	outputs := make([]Iterator, n)
+	for i := range outputs {
+		source, position := copies[i], i
+		outputs[i] = produce(func(send func(any) bool) {
+			defer Stop(source)
+			for value := range source {
+				tuple, ok := value.([]any)
+				if !ok {
+					send(fmt.Errorf("%w: %T is not a []any", ErrUnexpectedType, value))
+					return
+				}
+				if len(tuple) != n {
+					send(fmt.Errorf("%w: tuple has %d elements, expected %d", ErrUnequalLengths, len(tuple), n))
+					return
+				}
+				if !send(tuple[position]) {
+					return
+				}
+			}
+		})
+	}
+	return outputs
+}
+
+// Intern forwards every element of ch replaced by the first equal value it saw, so that equal strings, or structs holding
+// strings, share one backing copy, elements that are not a T send ErrUnexpectedType
+func Intern[T comparable](ch Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		canonical := make(map[T]T)
+		for value := range ch {
+			v, ok := value.(T)
+			if !ok {
+				send(fmt.Errorf("%w: %T is not a %T", ErrUnexpectedType, value, *new(T)))
+				return
+			}
+			if existing, ok := canonical[v]; ok {
+				v = existing
+			} else {
+				canonical[v] = v
+			}
+			if !send(v) {
+				return
+			}
+		}
+	})
+}
+
+// Runes yields each rune of s as a rune, decoding multi byte UTF-8 characters whole
+func Runes(s string) Iterator {
+	return produce(func(send func(any) bool) {
+		for _, r := range s {
+			if !send(r) {
+				return
+			}
+		}
+	})
+}
+
+// Grouper sends iterable in []T groups of exactly n elements, padding the last group with fill if it is short
+func Grouper[T any](iterable []T, n int, fill T) Iterator {
+	return produce(func(send func(any) bool) {
+		if n <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		for start := 0; start < len(iterable); start += n {
+			group := make([]T, n)
+			for i := range group {
+				group[i] = fill
+				if start+i < len(iterable) {
+					group[i] = iterable[start+i]
+				}
+			}
+			if !send(group) {
+				return
+			}
+		}
+	})
+}
+
+// FlatMapIter sends every element of the Iterator fn returns for each element of ch, one after another,
+// each of those Iterators is read to the end, or stopped and drained if the result is stopped early
+func FlatMapIter(ch Iterator, fn func(any) Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		for value := range ch {
+			sub := fn(value)
+			for element := range sub {
+				if !send(element) {
+					Stop(sub)
+					for range sub {
+					}
+					return
+				}
+			}
+		}
+	})
+}
+
+// FirstNDistinct reads ch until it has seen n distinct T values, or ch is exhausted, then stops ch and returns them
+// in the order they were first seen, elements that are not a T are skipped
+func FirstNDistinct[T comparable](ch Iterator, n int) []T {
+	defer Stop(ch)
+	seen := make(map[T]bool)
+	result := []T{}
+	for len(result) < n {
+		value, ok := <-ch
+		if !ok {
+			break
+		}
+		if v, ok := value.(T); ok && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// ZipForEach calls fn with the index and the elements at that index of each of iterables, up to the shortest of them,
+// stopping early if fn returns false, the tuple is reused between calls so copy it to keep it
+func ZipForEach[T any](fn func(index int, tuple []T) bool, iterables ...[]T) {
+	tuple := make([]T, len(iterables))
+	for index := 0; index < shortestLength(iterables); index++ {
+		for i, iterable := range iterables {
+			tuple[i] = iterable[index]
+		}
+		if !fn(index, tuple) {
+			return
+		}
+	}
+}
+
+// TopK drains ch keeping only the k largest T values according to less in a bounded heap, returning them largest first,
+// elements that are not a T are skipped
+func TopK[T any](ch Iterator, k int, less func(a, b T) bool) []T {
+	if k <= 0 {
+		for range ch {
+		}
+		return []T{}
+	}
+	h := &boundedHeap[T]{less: less}
+	for value := range ch {
+		v, ok := value.(T)
+		if !ok {
+			continue
+		}
+		if len(h.items) < k {
+			heap.Push(h, v)
+		} else if less(h.items[0], v) {
+			h.items[0] = v
+			heap.Fix(h, 0)
+		}
+	}
+	result := make([]T, len(h.items))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h).(T)
+	}
+	return result
+}
+
+// boundedHeap is a min heap ordered by less, implementing heap.Interface
+type boundedHeap[T any] struct {
+	items []T
+	less  func(a, b T) bool
+}
+
+func (h *boundedHeap[T]) Len() int           { return len(h.items) }
+func (h *boundedHeap[T]) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *boundedHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *boundedHeap[T]) Push(x any)         { h.items = append(h.items, x.(T)) }
+func (h *boundedHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// CumulativeMax sends the largest element of iterable seen so far at each position
+func CumulativeMax[T cmp.Ordered](iterable []T) Iterator {
+	return cumulativeExtreme(iterable, 1)
+}
+
+// CumulativeMin sends the smallest element of iterable seen so far at each position
+func CumulativeMin[T cmp.Ordered](iterable []T) Iterator {
+	return cumulativeExtreme(iterable, -1)
+}
+
+func cumulativeExtreme[T cmp.Ordered](iterable []T, want int) Iterator {
+	return produce(func(send func(any) bool) {
+		var extreme T
+		for i, element := range iterable {
+			if i == 0 || cmp.Compare(element, extreme) == want {
+				extreme = element
+			}
+			if !send(extreme) {
+				return
+			}
+		}
+	})
+}
+
+// SkipNonFinite forwards every element of ch except float32 and float64 values that are NaN or infinite
+func SkipNonFinite(ch Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		for value := range ch {
+			var f float64
+			switch v := value.(type) {
+			case float32:
+				f = float64(v)
+			case float64:
+				f = v
+			}
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				continue
+			}
+			if !send(value) {
+				return
+			}
+		}
+	})
+}
+
+// ToIndexedMap returns a map from each position of iterable to its element
+func ToIndexedMap[T any](iterable []T) map[int]T {
+	result := make(map[int]T, len(iterable))
+	for i, element := range iterable {
+		result[i] = element
+	}
+	return result
+}
+
+// ToIndexedMapIter drains a stream of []any{index, element} pairs, such as ZipCount yields, into a map from index to
+// element, so positions removed by earlier stages are left as gaps, it stops at the first malformed pair or error
+func ToIndexedMapIter[T any](ch Iterator) (map[int]T, error) {
+	defer Stop(ch)
+	result := make(map[int]T)
+	for value := range ch {
+		if err, ok := value.(error); ok {
+			return nil, err
+		}
+		pair, ok := value.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("%w: %v is not an index and element pair", ErrUnexpectedType, value)
+		}
+		index, okIndex := pair[0].(int)
+		element, okElement := pair[1].(T)
+		if !okIndex || !okElement {
+			return nil, fmt.Errorf("%w: %T and %T are not an int and %T", ErrUnexpectedType, pair[0], pair[1], *new(T))
+		}
+		result[index] = element
+	}
+	return result, nil
+}
+
+// WindowResult is the result of a function applied to the window starting at StartIndex
+type WindowResult[R any] struct {
+	StartIndex int
+	Value      R
+}
+
+// WindowedMap sends a WindowResult of fn applied to each full sliding window of the given size, fn is given its own copy of the window
+func WindowedMap[T any, R any](iterable []T, size int, fn func([]T) R) Iterator {
+	return produce(func(send func(any) bool) {
+		if size <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		for start := 0; start+size <= len(iterable); start++ {
+			window := append([]T{}, iterable[start:start+size]...)
+			if !send(WindowResult[R]{StartIndex: start, Value: fn(window)}) {
+				return
+			}
+		}
+	})
+}
+
+// DedupLRU forwards the elements of ch that are not among the capacity most recently seen distinct values, seeing a
+// suppressed value again makes it most recent, capacity of zero or less forwards everything
+func DedupLRU[T comparable](ch Iterator, capacity int) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		recent := list.New()
+		elements := make(map[T]*list.Element)
+		for value := range ch {
+			if capacity <= 0 {
+				if !send(value) {
+					return
+				}
+				continue
+			}
+			v, ok := value.(T)
+			if !ok {
+				send(fmt.Errorf("%w: %T is not a %T", ErrUnexpectedType, value, *new(T)))
+				return
+			}
+			if element, ok := elements[v]; ok {
+				recent.MoveToFront(element)
+				continue
+			}
+			elements[v] = recent.PushFront(v)
+			if recent.Len() > capacity {
+				delete(elements, recent.Remove(recent.Back()).(T))
+			}
+			if !send(v) {
+				return
+			}
+		}
+	})
+}
+
+// EqualMultiset drains a and b, reporting whether they yielded the same T values the same number of times in any order,
+// an element that is not a T makes them unequal
+func EqualMultiset[T comparable](a, b Iterator) bool {
+	counts := make(map[T]int)
+	equal := true
+	for value := range a {
+		v, ok := value.(T)
+		equal = equal && ok
+		counts[v]++
+	}
+	for value := range b {
+		v, ok := value.(T)
+		equal = equal && ok
+		counts[v]--
+	}
+	for _, count := range counts {
+		equal = equal && count == 0
+	}
+	return equal
+}
+
+// Add returns the elementwise sum of a and b, or ErrUnequalLengths if their lengths differ
+func Add[T Number](a, b []T) ([]T, error) {
+	return elementwise(a, b, func(x, y T) (T, error) { return x + y, nil })
+}
+
+// Sub returns the elementwise difference of a and b, or ErrUnequalLengths if their lengths differ
+func Sub[T Number](a, b []T) ([]T, error) {
+	return elementwise(a, b, func(x, y T) (T, error) { return x - y, nil })
+}
+
+// Mul returns the elementwise product of a and b, or ErrUnequalLengths if their lengths differ
+func Mul[T Number](a, b []T) ([]T, error) {
+	return elementwise(a, b, func(x, y T) (T, error) { return x * y, nil })
+}
+
+// Div returns the elementwise quotient of a and b, or ErrUnequalLengths if their lengths differ and ErrDivideByZero
+// if any element of b is zero
+func Div[T Number](a, b []T) ([]T, error) {
+	return elementwise(a, b, func(x, y T) (T, error) {
+		if y == 0 {
+			return 0, ErrDivideByZero
+		}
+		return x / y, nil
+	})
+}
+
+func elementwise[T Number](a, b []T, fn func(x, y T) (T, error)) ([]T, error) {
+	if len(a) != len(b) {
+		return nil, ErrUnequalLengths
+	}
+	result := make([]T, len(a))
+	for i := range a {
+		var err error
+		if result[i], err = fn(a[i], b[i]); err != nil {
+			return nil, fmt.Errorf("%w at index %d", err, i)
+		}
+	}
+	return result, nil
+}
+
+// MonotonicRuns sends iterable split into its longest []T runs that are non decreasing when increasing is true,
+// or non increasing when it is false, so equal neighbours always continue a run
+func MonotonicRuns[T cmp.Ordered](iterable []T, increasing bool) Iterator {
+	return SessionWindow(iterable, func(prev, cur T) bool {
+		if increasing {
+			return cur < prev
+		}
+		return cur > prev
+	})
+}
+
+// WeightedSample returns up to k elements of iterable chosen at random with r, each more likely to be picked the larger
+// its weight, using the A-Res reservoir algorithm, elements with a weight of zero or less are never picked
+func WeightedSample[T any](iterable []T, weightFn func(T) float64, k int, r *rand.Rand) []T {
+	if k <= 0 {
+		return []T{}
+	}
+	type keyed struct {
+		key     float64
+		element T
+	}
+	h := &boundedHeap[keyed]{less: func(a, b keyed) bool { return a.key < b.key }}
+	for _, element := range iterable {
+		weight := weightFn(element)
+		if weight <= 0 {
+			continue
+		}
+		item := keyed{key: math.Pow(r.Float64(), 1/weight), element: element}
+		if len(h.items) < k {
+			heap.Push(h, item)
+		} else if h.items[0].key < item.key {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+	}
+	result := make([]T, len(h.items))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h).(keyed).element
+	}
+	return result
+}
+
+// Debounce forwards an element of ch only once quiet has passed without a newer one arriving, so each burst of elements
+// is reduced to its last, the pending element is sent straight away when ch closes
+func Debounce(ch Iterator, quiet time.Duration) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		timer := time.NewTimer(quiet)
+		timer.Stop()
+		defer timer.Stop()
+		var pending any
+		hasPending := false
+		for {
+			select {
+			case value, ok := <-ch:
+				if !ok {
+					if hasPending {
+						send(pending)
+					}
+					return
+				}
+				if !timer.Stop() && hasPending {
+					<-timer.C
+				}
+				pending, hasPending = value, true
+				timer.Reset(quiet)
+			case <-timer.C:
+				hasPending = false
+				if !send(pending) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// ZipInto appends a T to dst for each row of fields, setting the exported fields of T in order from the columns and stopping
+// at the shortest column, it returns ErrFieldCount if the number of columns and fields differ
+func ZipInto[T any](dst *[]T, fields ...[]any) error {
+	indexes, err := exportedFields(reflect.TypeOf(*new(T)), len(fields))
+	if err != nil {
+		return err
+	}
+	for row := 0; row < shortestLength(fields); row++ {
+		var result T
+		if err := fillFields(reflect.ValueOf(&result).Elem(), indexes, fields, row); err != nil {
+			return err
+		}
+		*dst = append(*dst, result)
+	}
+	return nil
+}
+
+// GroupByStreaming sends a MapEntry[K, Iterator] for every run of consecutive elements of ch sharing the same key, whose
+// Value lazily yields that run's elements, a group's Iterator must be read to the end or stopped with Stop before the
+// next group is requested, otherwise both block, and once the next group is sent the previous Iterator is closed
+func GroupByStreaming[K comparable](ch Iterator, keyFn func(any) K) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		value, ok := <-ch
+		var key K
+		if ok {
+			key = keyFn(value)
+		}
+		for ok {
+			groupKey := key
+			group, sendGroup, finish := stoppable()
+			if !send(MapEntry[K, Iterator]{Key: groupKey, Value: group}) {
+				finish()
+				return
+			}
+			feeding := true
+			for ok && key == groupKey {
+				if feeding {
+					feeding = sendGroup(value)
+				}
+				if value, ok = <-ch; ok {
+					key = keyFn(value)
+				}
+			}
+			finish()
+		}
+	})
+}
+
+// AllEqual reports whether every element of iterable equals the first, which is true for an empty iterable
+func AllEqual[T comparable](iterable []T) bool {
+	for _, element := range iterable {
+		if element != iterable[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// AllEqualIter reports whether every element of ch is reflect.DeepEqual to the first, stopping ch at the first that is not
+func AllEqualIter(ch Iterator) bool {
+	defer Stop(ch)
+	first, ok := <-ch
+	if !ok {
+		return true
+	}
+	for value := range ch {
+		if !reflect.DeepEqual(value, first) {
+			return false
+		}
+	}
+	return true
+}
+
+// PMapChunked splits iterable into chunks of chunkSize elements, applies fn to them across workers goroutines and sends
+// every result in the original order, chunking spreads the cost of handing work to goroutines over many elements
+func PMapChunked[T any, R any](iterable []T, chunkSize, workers int, fn func([]T) []R) Iterator {
+	return produce(func(send func(any) bool) {
+		if chunkSize <= 0 || workers <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		results := make([]chan []R, (len(iterable)+chunkSize-1)/chunkSize)
+		for i := range results {
+			results[i] = make(chan []R, 1)
+		}
+		jobs := make(chan int)
+		quit := make(chan struct{})
+		defer close(quit)
+		go func() {
+			defer close(jobs)
+			for i := range results {
+				select {
+				case jobs <- i:
+				case <-quit:
+					return
+				}
+			}
+		}()
+		for w := 0; w < workers; w++ {
+			go func() {
+				for i := range jobs {
+					end := (i + 1) * chunkSize
+					if end > len(iterable) {
+						end = len(iterable)
+					}
+					results[i] <- fn(iterable[i*chunkSize : end])
+				}
+			}()
+		}
+		for _, result := range results {
+			for _, value := range <-result {
+				if !send(value) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// MapReduce applies mapFn to every element of iterable and folds the results with reduceFn across workers goroutines,
+// each taking a contiguous share, then combines their partial results in order and returns finalize of the total,
+// reduceFn must be associative, workers below 1 is treated as 1, and an empty iterable gives finalize of the zero M
+func MapReduce[T any, M any, R any](iterable []T, mapFn func(T) M, reduceFn func(a, b M) M, workers int, finalize func(M) R) R {
+	workers = max(min(workers, len(iterable)), 1)
+	partials := make([]M, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := w*len(iterable)/workers, (w+1)*len(iterable)/workers
+		if start == end {
+			continue
+		}
+		wg.Add(1)
+		go func(w int, share []T) {
+			defer wg.Done()
+			acc := mapFn(share[0])
+			for _, element := range share[1:] {
+				acc = reduceFn(acc, mapFn(element))
+			}
+			partials[w] = acc
+		}(w, iterable[start:end])
+	}
+	wg.Wait()
+	var total M
+	if len(iterable) > 0 {
+		total = partials[0]
+		for _, partial := range partials[1:] {
+			total = reduceFn(total, partial)
+		}
+	}
+	return finalize(total)
+}
+
+// Indices sends the index of every element of iterable for which pred returns true
+func Indices[T any](iterable []T, pred func(T) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		for i, element := range iterable {
+			if pred(element) {
+				if !send(i) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Sum returns the total of every element of iterable
+func Sum[T Number](iterable []T) T {
+	var total T
+	for _, element := range iterable {
+		total += element
+	}
+	return total
+}
+
+// SumColumns returns the total of each column of rows, or ErrUnequalLengths if the rows are ragged
+func SumColumns[T Number](rows [][]T) ([]T, error) {
+	columns, err := Transpose(rows)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]T, len(columns))
+	for i, column := range columns {
+		result[i] = Sum(column)
+	}
+	return result, nil
+}
+
+// FillGaps sends every element of the strictly ascending iterable along with the values step apart between neighbours,
+// sending ErrUnsorted and stopping at the first element not greater than the one before it
+func FillGaps(iterable []int, step int) Iterator {
+	return produce(func(send func(any) bool) {
+		if step <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		for i, element := range iterable {
+			if i > 0 {
+				previous := iterable[i-1]
+				if element <= previous {
+					send(fmt.Errorf("%w: %d follows %d at index %d", ErrUnsorted, element, previous, i))
+					return
+				}
+				for value := previous + step; value < element; value += step {
+					if !send(value) {
+						return
+					}
+				}
+			}
+			if !send(element) {
+				return
+			}
+		}
+	})
+}
+
+// ChainReflect sends every element of each of iterables in order, whatever their element types, sending
+// ErrUnexpectedType and stopping at any argument that is not a slice or array
+func ChainReflect(iterables ...any) Iterator {
+	return produce(func(send func(any) bool) {
+		for i, slice := range iterables {
+			value := reflect.ValueOf(slice)
+			if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+				send(fmt.Errorf("%w: argument %d is a %T, not a slice", ErrUnexpectedType, i, slice))
+				return
+			}
+			for index := 0; index < value.Len(); index++ {
+				if !send(value.Index(index).Interface()) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// RollingStdDev sends the sample standard deviation of each full sliding window of the given size as a float64, updating
+// a running sum and sum of squares as the window slides, a window of 1 has no spread so yields 0
+func RollingStdDev[T Number](iterable []T, window int) Iterator {
+	return produce(func(send func(any) bool) {
+		if window <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		var sum, sumSquares float64
+		n := float64(window)
+		for i, element := range iterable {
+			x := float64(element)
+			sum += x
+			sumSquares += x * x
+			if i >= window {
+				old := float64(iterable[i-window])
+				sum -= old
+				sumSquares -= old * old
+			}
+			if i < window-1 {
+				continue
+			}
+			if window == 1 {
+				if !send(0.0) {
+					return
+				}
+				continue
+			}
+			variance := (sumSquares - sum*sum/n) / (n - 1)
+			if !send(math.Sqrt(math.Max(variance, 0))) {
+				return
+			}
+		}
+	})
+}
+
+// SortedJoin sends a Pair for every element of a and element of b whose keys are equal, like JoinBy but by merging runs of
+// equal keys in O(n+m) without a map, both a and b must already be sorted in ascending key order
+func SortedJoin[A any, B any, K cmp.Ordered](a []A, b []B, keyA func(A) K, keyB func(B) K) Iterator {
+	return produce(func(send func(any) bool) {
+		i, j := 0, 0
+		for i < len(a) && j < len(b) {
+			left, right := keyA(a[i]), keyB(b[j])
+			switch cmp.Compare(left, right) {
+			case -1:
+				i++
+			case 1:
+				j++
+			default:
+				end := j
+				for end < len(b) && cmp.Compare(keyB(b[end]), left) == 0 {
+					end++
+				}
+				for ; i < len(a) && cmp.Compare(keyA(a[i]), left) == 0; i++ {
+					for _, element := range b[j:end] {
+						if !send(Pair[A, B]{First: a[i], Second: element}) {
+							return
+						}
+					}
+				}
+				j = end
+			}
+		}
+	})
+}
+
+// GroupUntil gathers iterable into []T groups, sending the current group and starting a new one whenever
+// shouldClose(group, next) is true, the final group is always sent
+func GroupUntil[T any](iterable []T, shouldClose func(group []T, next T) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		var group []T
+		for _, element := range iterable {
+			if len(group) > 0 && shouldClose(group, element) {
+				if !send(group) {
+					return
+				}
+				group = nil
+			}
+			group = append(group, element)
+		}
+		if len(group) > 0 {
+			if !send(group) {
+				return
+			}
+		}
+	})
+}
+
+// EnumerateRunes yields a Pair of the byte offset and rune for each rune of s, as for i, r := range s would
+func EnumerateRunes(s string) Iterator {
+	return produce(func(send func(any) bool) {
+		for offset, r := range s {
+			if !send(Pair[int, rune]{First: offset, Second: r}) {
+				return
+			}
+		}
+	})
+}
+
+// Distribute deals iterable round robin into n slices, so element i goes to slice i%n, or returns ErrInvalidSize if n is not above zero
+func Distribute[T any](iterable []T, n int) ([][]T, error) {
+	if n <= 0 {
+		return nil, ErrInvalidSize
+	}
+	result := make([][]T, n)
+	for i, element := range iterable {
+		result[i%n] = append(result[i%n], element)
+	}
+	return result, nil
+}
+
+// FilterPairs keeps the aligned elements of as and bs for which keep returns true, or returns ErrUnequalLengths if their lengths differ
+func FilterPairs[A any, B any](as []A, bs []B, keep func(A, B) bool) ([]A, []B, error) {
+	if len(as) != len(bs) {
+		return nil, nil, ErrUnequalLengths
+	}
+	keptA, keptB := []A{}, []B{}
+	for i := range as {
+		if keep(as[i], bs[i]) {
+			keptA = append(keptA, as[i])
+			keptB = append(keptB, bs[i])
+		}
+	}
+	return keptA, keptB, nil
+}
+
+// ToMapMerge builds a map from keyFn to valFn of each element of iterable, the first value for a key is stored as is
+// and each later one is combined with the stored value by merge
+func ToMapMerge[T any, K comparable, V any](iterable []T, keyFn func(T) K, valFn func(T) V, merge func(existing, new V) V) map[K]V {
+	result := make(map[K]V)
+	for _, element := range iterable {
+		key, value := keyFn(element), valFn(element)
+		if existing, ok := result[key]; ok {
+			value = merge(existing, value)
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// WriteTo drains ch writing format of each element to w, returning the bytes written and stopping ch at the first write
+// error, or at the first element that is itself an error which is returned
+func WriteTo(ch Iterator, w io.Writer, format func(any) []byte) (int64, error) {
+	defer Stop(ch)
+	var written int64
+	for value := range ch {
+		if err, ok := value.(error); ok {
+			return written, err
+		}
+		n, err := w.Write(format(value))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// WriteCSV drains ch writing toRow of each element to w and flushing it at the end, it stops ch and returns at the first
+// error from w or at the first element that is itself an error
+func WriteCSV(ch Iterator, w *csv.Writer, toRow func(any) []string) error {
+	defer Stop(ch)
+	for value := range ch {
+		if err, ok := value.(error); ok {
+			w.Flush()
+			return err
+		}
+		if err := w.Write(toRow(value)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// RollingMode sends the most frequent value of each full window, ties going to the value seen earliest in the window
+func RollingMode[T comparable](iterable []T, window int) Iterator {
+	return produce(func(send func(any) bool) {
+		if window <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		counts := make(map[T]int)
+		for i, element := range iterable {
+			counts[element]++
+			if i >= window {
+				old := iterable[i-window]
+				if counts[old]--; counts[old] == 0 {
+					delete(counts, old)
+				}
+			}
+			if i < window-1 {
+				continue
+			}
+			mode, best := iterable[i-window+1], 0
+			for _, candidate := range iterable[i-window+1 : i+1] {
+				if counts[candidate] > best {
+					mode, best = candidate, counts[candidate]
+				}
+			}
+			if !send(mode) {
+				return
+			}
+		}
+	})
+}
+
+// DivergePoints sends a Pair of the index and the []any values at each position where the iterables do not all
+// agree, or only ErrUnequalLengths if they are not all the same length
+func DivergePoints[T comparable](iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
+		if len(iterables) == 0 {
+			return
+		}
+		if !ensureSameLength(iterables) {
+			send(ErrUnequalLengths)
+			return
+		}
+		for index := range iterables[0] {
+			for _, iterable := range iterables[1:] {
+				if iterable[index] != iterables[0][index] {
+					values := make([]any, len(iterables))
+					for i, iterable := range iterables {
+						values[i] = iterable[index]
+					}
+					if !send(Pair[int, []any]{First: index, Second: values}) {
+						return
+					}
+					break
+				}
+			}
+		}
+	})
+}
+
+// TokenBucket forwards elements of ch at a sustained rate per second, allowing bursts of up to burst elements, an element
+// arriving when no token is left is dropped, or if block is true held until a token is available, errors are always
+// forwarded without using a token, ErrInvalidSize is sent if rate or burst is not greater than zero
+func TokenBucket(ch Iterator, rate float64, burst int, block bool) Iterator {
+	return tokenBucket(ch, rate, burst, block, time.Now, time.Sleep)
+}
+
+func tokenBucket(ch Iterator, rate float64, burst int, block bool, now func() time.Time, sleep func(time.Duration)) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		if rate <= 0 || burst <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		tokens, last := float64(burst), now()
+		for value := range ch {
+			if _, ok := value.(error); ok {
+				if !send(value) {
+					return
+				}
+				continue
+			}
+			current := now()
+			tokens = math.Min(float64(burst), tokens+current.Sub(last).Seconds()*rate)
+			last = current
+			if tokens < 1 {
+				if !block {
+					continue
+				}
+				wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+				sleep(wait)
+				last = last.Add(wait)
+				tokens = 1
+			}
+			tokens--
+			if !send(value) {
+				return
+			}
+		}
+	})
+}
+
+// PartitionByKey routes each element of ch to the Iterator of its key in keys, elements with any other key are dropped and
+// errors are sent to every Iterator, the Iterators are fed in step so they must be read concurrently, an Iterator
+// that is stopped receives nothing more and ch is stopped once all of them are
+func PartitionByKey[K comparable](ch Iterator, keyFn func(any) K, keys []K) map[K]Iterator {
+	outputs := make(map[K]Iterator, len(keys))
+	sends := make(map[K]func(any) bool, len(keys))
+	finishes := make([]func(), 0, len(keys))
+	for _, key := range keys {
+		if _, ok := outputs[key]; ok {
+			continue
+		}
+		out, send, finish := stoppable()
+		outputs[key], sends[key] = out, send
+		finishes = append(finishes, finish)
+	}
+	go func() {
+		defer func() {
+			for _, finish := range finishes {
+				finish()
+			}
+		}()
+		defer Stop(ch)
+		for value := range ch {
+			if _, ok := value.(error); ok {
+				for key, send := range sends {
+					if !send(value) {
+						delete(sends, key)
+					}
+				}
+			} else if key := keyFn(value); sends[key] != nil && !sends[key](value) {
+				delete(sends, key)
+			}
+			if len(sends) == 0 {
+				return
+			}
+		}
+	}()
+	return outputs
+}
+
+// RunningDistinct sends, for each element of iterable, a new []T of the distinct values seen so far in first seen order
+func RunningDistinct[T comparable](iterable []T) Iterator {
+	return produce(func(send func(any) bool) {
+		seen := make(map[T]bool)
+		var distinct []T
+		for _, element := range iterable {
+			if !seen[element] {
+				seen[element] = true
+				distinct = append(distinct, element)
+			}
+			if !send(slices.Clone(distinct)) {
+				return
+			}
+		}
+	})
+}
+
+// Sorted sends the elements of iterable in ascending order, sorting a copy so iterable is left untouched
+func Sorted[T cmp.Ordered](iterable []T) Iterator {
+	return SortedBy(iterable, cmp.Less[T])
+}
+
+// SortedBy sends the elements of iterable in the order given by less, keeping equal elements in their original order
+// and sorting a copy so iterable is left untouched
+func SortedBy[T any](iterable []T, less func(a, b T) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		sorted := slices.Clone(iterable)
+		slices.SortStableFunc(sorted, func(a, b T) int {
+			if less(a, b) {
+				return -1
+			}
+			if less(b, a) {
+				return 1
+			}
+			return 0
+		})
+		for _, element := range sorted {
+			if !send(element) {
+				return
+			}
+		}
+	})
+}
+
+// Peekable reads from an Iterator while allowing up to a fixed number of upcoming elements to be looked at first
+type Peekable struct {
+	ch        Iterator
+	lookahead int
+	buffer    []any
+}
+
+// Buffered returns a Peekable over ch that can look up to lookahead elements ahead, lookahead below 1 is treated as 1
+func Buffered(ch Iterator, lookahead int) *Peekable {
+	return &Peekable{ch: ch, lookahead: max(lookahead, 1)}
+}
+
+// Next returns the next element, or false once ch is exhausted
+func (p *Peekable) Next() (any, bool) {
+	if !p.fill(1) {
+		return nil, false
+	}
+	value := p.buffer[0]
+	p.buffer[0] = nil
+	p.buffer = p.buffer[1:]
+	return value, true
+}
+
+// Peek returns the next element without consuming it, or false once ch is exhausted
+func (p *Peekable) Peek() (any, bool) {
+	if !p.fill(1) {
+		return nil, false
+	}
+	return p.buffer[0], true
+}
+
+// PeekN returns up to the next k elements without consuming them, reporting whether k were available, k is limited to
+// the lookahead given to Buffered and k below 1 peeks nothing
+func (p *Peekable) PeekN(k int) ([]any, bool) {
+	if k <= 0 {
+		return []any{}, true
+	}
+	if k > p.lookahead {
+		p.fill(p.lookahead)
+		return slices.Clone(p.buffer), false
+	}
+	ok := p.fill(k)
+	return slices.Clone(p.buffer[:min(k, len(p.buffer))]), ok
+}
+
+// Stop stops ch and drops any buffered elements
+func (p *Peekable) Stop() {
+	Stop(p.ch)
+	p.buffer = nil
+}
+
+// fill reads from ch until at least n elements are buffered, reporting whether it got that many
+func (p *Peekable) fill(n int) bool {
+	for len(p.buffer) < n {
+		value, ok := <-p.ch
+		if !ok {
+			return false
+		}
+		p.buffer = append(p.buffer, value)
+	}
+	return true
+}
+
+// ZipRow is a row sent by ZipReport, Values holds the zero value at the indexes listed in Exhausted
+type ZipRow[T any] struct {
+	Index     int
+	Values    []T
+	Exhausted []int
+}
+
+// ZipReport sends a ZipRow for every index up to the longest of iterables, listing the inputs that have run out by then
+func ZipReport[T any](iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
+		longest := 0
+		for _, iterable := range iterables {
+			longest = max(longest, len(iterable))
+		}
+		for index := 0; index < longest; index++ {
+			row := ZipRow[T]{Index: index, Values: make([]T, len(iterables))}
+			for i, iterable := range iterables {
+				if index < len(iterable) {
+					row.Values[i] = iterable[index]
+				} else {
+					row.Exhausted = append(row.Exhausted, i)
+				}
+			}
+			if !send(row) {
+				return
+			}
+		}
+	})
+}
+
+// Generator is a function that emits values by calling yield, it should return as soon as yield reports false
+type Generator func(yield func(any) bool)
+
+// NewGenerator returns an Iterator of the values yielded by gen, which runs in its own goroutine, yield reports false
+// once the Iterator is stopped with Stop, for example by Take, so gen can return without leaking
+func NewGenerator(gen Generator) Iterator {
+	return produce(gen)
+}
+
+// Clamp sends each element of ch limited to the range lo to hi, stopping with ErrUnexpectedType at an element that is
+// not a T, errors from ch are forwarded before stopping
+func Clamp[T cmp.Ordered](ch Iterator, lo, hi T) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		for value := range ch {
+			switch v := value.(type) {
+			case T:
+				if !send(min(max(v, lo), hi)) {
+					return
+				}
+			case error:
+				send(v)
+				return
+			default:
+				send(fmt.Errorf("%w: %T is not a %T", ErrUnexpectedType, value, lo))
+				return
+			}
+		}
+	})
+}
+
+// ZipMasked sends a Pair for every index up to the longest of iterables, of the values at that index, holding the zero
+// value for inputs that have run out, and a mask that is true for the inputs that had a value
+func ZipMasked[T any](iterables ...[]T) Iterator {
+	return produce(func(send func(any) bool) {
+		longest := 0
+		for _, iterable := range iterables {
+			longest = max(longest, len(iterable))
+		}
+		for index := 0; index < longest; index++ {
+			values, mask := make([]T, len(iterables)), make([]bool, len(iterables))
+			for i, iterable := range iterables {
+				if index < len(iterable) {
+					values[i], mask[i] = iterable[index], true
+				}
+			}
+			if !send(Pair[[]T, []bool]{First: values, Second: mask}) {
+				return
+			}
+		}
+	})
+}
+
+// FlattenTree lazily sends root and every node below it in depth first pre-order, using children to find the nodes
+// below each node, a cyclic graph is walked forever so must be cut short with Take or Stop
+func FlattenTree[T any](root T, children func(T) []T) Iterator {
+	return produce(func(send func(any) bool) {
+		stack := []T{root}
+		for len(stack) > 0 {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !send(node) {
+				return
+			}
+			below := children(node)
+			for i := len(below) - 1; i >= 0; i-- {
+				stack = append(stack, below[i])
+			}
+		}
+	})
+}
+
+// FlattenTreeBFS is like FlattenTree but sends the nodes in breadth first order, level by level
+func FlattenTreeBFS[T any](root T, children func(T) []T) Iterator {
+	return produce(func(send func(any) bool) {
+		queue := list.New()
+		queue.PushBack(root)
+		for queue.Len() > 0 {
+			node := queue.Remove(queue.Front()).(T)
+			if !send(node) {
+				return
+			}
+			for _, child := range children(node) {
+				queue.PushBack(child)
+			}
+		}
+	})
+}
+
+// ThrottleByKey forwards an element of ch only if at least minInterval has passed since the last element forwarded with
+// the same key, as computed by keyFn, dropping the rest so each key is paced on its own, errors are always forwarded
+func ThrottleByKey[K comparable](ch Iterator, keyFn func(any) K, minInterval time.Duration) Iterator {
+	return throttleByKey(ch, keyFn, minInterval, time.Now)
+}
+
+func throttleByKey[K comparable](ch Iterator, keyFn func(any) K, minInterval time.Duration, now func() time.Time) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		last := make(map[K]time.Time)
+		for value := range ch {
+			if _, ok := value.(error); !ok {
+				key, current := keyFn(value), now()
+				if previous, ok := last[key]; ok && current.Sub(previous) < minInterval {
+					continue
+				}
+				last[key] = current
+			}
+			if !send(value) {
+				return
+			}
+		}
+	})
+}
+
+// ZipJSON sends a map[string]any for every []any row of rows, keyed by the matching entry of header, stopping with
+// ErrUnequalLengths at a row of a different width or ErrUnexpectedType at an element that is not a []any
+func ZipJSON(header []string, rows Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(rows)
+		index := 0
+		for value := range rows {
+			row, ok := value.([]any)
+			switch {
+			case !ok:
+				send(fmt.Errorf("%w: row %d is a %T", ErrUnexpectedType, index, value))
+				return
+			case len(row) != len(header):
+				send(fmt.Errorf("%w: row %d has %d values for %d headers", ErrUnequalLengths, index, len(row), len(header)))
+				return
+			}
+			object := make(map[string]any, len(header))
+			for i, name := range header {
+				object[name] = row[i]
+			}
+			if !send(object) {
+				return
+			}
+			index++
+		}
+	})
+}
+
+// RandomSkip forwards each element of ch independently with probability keepProb, drawing from r so a seeded r gives the
+// same selection every time, errors are always forwarded and keepProb must be between 0 and 1
+func RandomSkip(ch Iterator, keepProb float64, r *rand.Rand) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		if keepProb < 0 || keepProb > 1 || math.IsNaN(keepProb) {
+			send(fmt.Errorf("%w: probability %v is not between 0 and 1", ErrOutOfRange, keepProb))
+			return
+		}
+		for value := range ch {
+			if _, ok := value.(error); !ok && r.Float64() >= keepProb {
+				continue
+			}
+			if !send(value) {
+				return
+			}
+		}
+	})
+}
+
+// The Op values of a Change
+const (
+	OpAdded   = "added"
+	OpRemoved = "removed"
+	OpChanged = "changed"
+	OpEqual   = "equal"
+)
+
+// Change describes one position of a Diff, Old or New is the zero value when the position is added or removed
+type Change[T any] struct {
+	Index int
+	Op    string
+	Old   T
+	New   T
+}
+
+// Diff sends a Change for every index up to the longer of before and after, comparing them position by position,
+// unchanged positions are skipped if skipEqual is true
+func Diff[T comparable](before, after []T, skipEqual bool) Iterator {
+	return produce(func(send func(any) bool) {
+		for index := 0; index < max(len(before), len(after)); index++ {
+			change := Change[T]{Index: index}
+			switch {
+			case index >= len(before):
+				change.Op, change.New = OpAdded, after[index]
+			case index >= len(after):
+				change.Op, change.Old = OpRemoved, before[index]
+			case before[index] != after[index]:
+				change.Op, change.Old, change.New = OpChanged, before[index], after[index]
+			case skipEqual:
+				continue
+			default:
+				change.Op, change.Old, change.New = OpEqual, before[index], after[index]
+			}
+			if !send(change) {
+				return
+			}
+		}
+	})
+}
+
+// CountDistinct drains ch and returns the number of distinct elements, elements that are not a T are skipped
+func CountDistinct[T comparable](ch Iterator) int {
+	seen := make(map[T]struct{})
+	for value := range ch {
+		if v, ok := value.(T); ok {
+			seen[v] = struct{}{}
+		}
+	}
+	return len(seen)
+}
+
+// hllPrecision is the number of hash bits CountDistinctApprox uses to pick a register, giving 2^14 registers
+const hllPrecision = 14
+
+// CountDistinctApprox drains ch and estimates the number of distinct elements with a HyperLogLog sketch of 16KiB,
+// whatever the size of the stream, elements are told apart by their type and fmt %v form and the estimate has a
+// standard error of about 0.8%
+func CountDistinctApprox(ch Iterator) int {
+	const m = 1 << hllPrecision
+	registers := make([]uint8, m)
+	for value := range ch {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%T:%v", value, value)
+		x := h.Sum64()
+		// mix the bits as FNV alone spreads short inputs poorly over the high bits
+		x ^= x >> 33
+		x *= 0xff51afd7ed558ccd
+		x ^= x >> 33
+		x *= 0xc4ceb9fe1a85ec53
+		x ^= x >> 33
+		index := x >> (64 - hllPrecision)
+		rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+		registers[index] = max(registers[index], rank)
+	}
+	sum, zeros := 0.0, 0
+	for _, register := range registers {
+		sum += math.Ldexp(1, -int(register))
+		if register == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(float64(m)/float64(zeros))
+	}
+	return int(math.Round(estimate))
+}
+
+// ExpandingWindow sends a new []T of every prefix of iterable, from the first element alone up to the whole of it
+func ExpandingWindow[T any](iterable []T) Iterator {
+	return produce(func(send func(any) bool) {
+		for end := 1; end <= len(iterable); end++ {
+			if !send(slices.Clone(iterable[:end])) {
+				return
+			}
+		}
+	})
+}
+
+// MergeByPriority merges sources by repeatedly sending, out of the next element of every source not yet exhausted, the
+// one that priority ranks first, ties going to the earlier source, so sources each ordered by priority merge into one
+// ordered stream, every source is stopped once the merge ends
+func MergeByPriority(sources []Iterator, priority func(a, b any) bool) Iterator {
+	type head struct {
+		value  any
+		source int
+	}
+	return produce(func(send func(any) bool) {
+		defer func() {
+			for _, source := range sources {
+				Stop(source)
+			}
+		}()
+		h := &boundedHeap[head]{less: func(a, b head) bool {
+			if priority(a.value, b.value) {
+				return true
+			}
+			return !priority(b.value, a.value) && a.source < b.source
+		}}
+		for i, source := range sources {
+			if value, ok := <-source; ok {
+				h.items = append(h.items, head{value: value, source: i})
+			}
+		}
+		heap.Init(h)
+		for h.Len() > 0 {
+			next := h.items[0]
+			if !send(next.value) {
+				return
+			}
+			if value, ok := <-sources[next.source]; ok {
+				h.items[0].value = value
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+		}
+	})
+}
+
+// Unbatch sends every element of each slice in ch in turn, undoing Segment or BatchWeighted, it stops with
+// ErrUnexpectedType at an element that is not a slice and errors from ch are forwarded before stopping
+func Unbatch(ch Iterator) Iterator {
+	return produce(func(send func(any) bool) {
+		defer Stop(ch)
+		for value := range ch {
+			if err, ok := value.(error); ok {
+				send(err)
+				return
+			}
+			batch := reflect.ValueOf(value)
+			if batch.Kind() != reflect.Slice {
+				send(fmt.Errorf("%w: %T is not a slice", ErrUnexpectedType, value))
+				return
+			}
+			for i := 0; i < batch.Len(); i++ {
+				if !send(batch.Index(i).Interface()) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Covariance returns the sample covariance of x and y, or ErrUnequalLengths if their lengths differ and ErrInvalidSize
+// if they hold fewer than two elements
+func Covariance[T Number](x, y []T) (float64, error) {
+	sum, _, _, err := comoments(x, y)
+	if err != nil {
+		return 0, err
+	}
+	return sum / float64(len(x)-1), nil
+}
+
+// Correlation returns the Pearson correlation of x and y, failing as Covariance does or with ErrDivideByZero if either
+// is constant
+func Correlation[T Number](x, y []T) (float64, error) {
+	sum, sumX, sumY, err := comoments(x, y)
+	if err != nil {
+		return 0, err
+	}
+	if sumX == 0 || sumY == 0 {
+		return 0, ErrDivideByZero
+	}
+	return sum / math.Sqrt(sumX*sumY), nil
+}
+
+// comoments returns the sums of the products of the deviations of x and y from their means, with each other and with
+// themselves
+func comoments[T Number](x, y []T) (xy, xx, yy float64, err error) {
+	if len(x) != len(y) {
+		return 0, 0, 0, ErrUnequalLengths
+	}
+	if len(x) < 2 {
+		return 0, 0, 0, fmt.Errorf("%w: need at least 2 elements, got %d", ErrInvalidSize, len(x))
+	}
+	var meanX, meanY float64
+	for i := range x {
+		meanX += float64(x[i])
+		meanY += float64(y[i])
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+	for i := range x {
+		dx, dy := float64(x[i])-meanX, float64(y[i])-meanY
+		xy += dx * dy
+		xx += dx * dx
+		yy += dy * dy
+	}
+	return xy, xx, yy, nil
+}
+
+// Diff1 sends the difference between each element of iterable and the one before it, one fewer than there are elements
+func Diff1[T Number](iterable []T) Iterator {
+	return DiffN(iterable, 1)
+}
+
+// DiffN sends the difference between each element of iterable and the one lag places before it, sending ErrInvalidSize
+// if lag is not greater than zero
+func DiffN[T Number](iterable []T, lag int) Iterator {
+	return produce(func(send func(any) bool) {
+		if lag <= 0 {
+			send(ErrInvalidSize)
+			return
+		}
+		for i := lag; i < len(iterable); i++ {
+			if !send(iterable[i] - iterable[i-lag]) {
+				return
+			}
+		}
+	})
+}
+
+// FillForward sends iterable with every element for which isMissing is true replaced by the last element that was not,
+// missing elements before the first present one are sent unchanged
+func FillForward[T any](iterable []T, isMissing func(T) bool) Iterator {
+	return produce(func(send func(any) bool) {
+		var last T
+		seen := false
+		for _, element := range iterable {
+			if !isMissing(element) {
+				last, seen = element, true
+			} else if seen {
+				element = last
+			}
+			if !send(element) {
+				return
+			}
+		}
+	})
 }
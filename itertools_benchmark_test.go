@@ -3,6 +3,7 @@ package itertools
 import (
 	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -196,3 +197,101 @@ func BenchmarkCompress(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkTopK(b *testing.B) {
+	arr := rand.Perm(repeatTimes * 100)
+	less := func(x, y int) bool { return x < y }
+	for i := 0; i < b.N; i++ {
+		result := TopK(Iter(arr), 10, less)
+		if len(result) != 10 {
+			b.Log("TopK result not long enough")
+			b.Fail()
+		}
+	}
+}
+
+func BenchmarkTopKCollectSort(b *testing.B) {
+	arr := rand.Perm(repeatTimes * 100)
+	for i := 0; i < b.N; i++ {
+		var collected []int
+		for value := range Iter(arr) {
+			collected = append(collected, value.(int))
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(collected)))
+		if len(collected[:10]) != 10 {
+			b.Log("collect then sort result not long enough")
+			b.Fail()
+		}
+	}
+}
+
+func benchmarkPMapChunked(b *testing.B, chunkSize int) {
+	arr := rand.Perm(repeatTimes * 10)
+	square := func(chunk []int) []int {
+		result := make([]int, len(chunk))
+		for i, x := range chunk {
+			result[i] = x * x
+		}
+		return result
+	}
+	for i := 0; i < b.N; i++ {
+		counter := 0
+		for range PMapChunked(arr, chunkSize, 4, square) {
+			counter++
+		}
+		if counter != len(arr) {
+			b.Log("PMapChunked result not long enough")
+			b.Fail()
+		}
+	}
+}
+
+func BenchmarkPMapChunked(b *testing.B) {
+	benchmarkPMapChunked(b, 500)
+}
+
+func BenchmarkPMapChunkedPerElement(b *testing.B) {
+	benchmarkPMapChunked(b, 1)
+}
+
+func BenchmarkCollect(b *testing.B) {
+	arr := rand.Perm(repeatTimes * 100)
+	for i := 0; i < b.N; i++ {
+		if len(Collect[int](Iter(arr))) != len(arr) {
+			b.Log("Collect result not long enough")
+			b.Fail()
+		}
+	}
+}
+
+func BenchmarkCollectCap(b *testing.B) {
+	arr := rand.Perm(repeatTimes * 100)
+	for i := 0; i < b.N; i++ {
+		if len(CollectCap[int](Iter(arr), len(arr))) != len(arr) {
+			b.Log("CollectCap result not long enough")
+			b.Fail()
+		}
+	}
+}
+
+func BenchmarkWindowView(b *testing.B) {
+	arr := rand.Perm(repeatTimes * 100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sum := 0
+		for value := range WindowView(arr, 10) {
+			sum += value.([]int)[0]
+		}
+	}
+}
+
+func BenchmarkWindowCopy(b *testing.B) {
+	arr := rand.Perm(repeatTimes * 100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sum := 0
+		for value := range Segment(arr, 10, true) {
+			sum += value.([]int)[0]
+		}
+	}
+}
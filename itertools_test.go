@@ -1,12 +1,27 @@
 package itertools
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"math/rand"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unsafe"
 )
 
-func ExampleIterInt() {
+func ExampleIter() {
 	arr := []int{1, 2, 3, 4}
 	ch := Iter(arr)
 	for value := range ch {
@@ -60,7 +75,7 @@ func ExampleZip() {
 	// Output: [1 4 7][2 5 8][3 6 9]
 }
 
-func ExampleZipFailure() {
+func ExampleZip_failure() {
 	first := []int{1, 2, 3}
 	second := []int{4, 5, 6}
 	third := []int{7, 8, 9, 11}
@@ -68,7 +83,31 @@ func ExampleZipFailure() {
 	for value := range ch {
 		fmt.Printf("%v", value)
 	}
-	// Output: all parameters must be of the same length
+	// Output: [1 4 7][2 5 8][3 6 9]all parameters must be of the same length
+}
+
+func ExampleZipStrictAt() {
+	ch, divergedAt := ZipStrictAt([]int{1, 2, 3}, []int{4, 5})
+	for value := range ch {
+		fmt.Printf("%v ", value)
+	}
+	fmt.Println(divergedAt())
+	// Output: [1 4] [2 5] all parameters must be of the same length 2
+}
+
+func TestZipStrictAtEqual(t *testing.T) {
+	ch, divergedAt := ZipStrictAt([]string{"a", "b"}, []string{"c", "d"})
+	var got []any
+	for value := range ch {
+		got = append(got, value)
+	}
+	if len(got) != 2 || divergedAt() != -1 {
+		t.Error("expected 2 tuples and no divergence, got: ", got, divergedAt())
+	}
+	ch, divergedAt = ZipStrictAt([]string{}, []string{"a"}, []string{"a", "b"})
+	if value := <-ch; value != ErrUnequalLengths || divergedAt() != 0 {
+		t.Error("expected ErrUnequalLengths at 0, got: ", value, divergedAt())
+	}
 }
 
 func ExampleChain() {
@@ -90,7 +129,7 @@ func ExampleCount() {
 	// Output: 135
 }
 
-func ExampleCountDec() {
+func ExampleCount_decimal() {
 	ch := Count(1.5, 0.5)
 	for i := 0; i < 4; i++ {
 		fmt.Printf("%v:", Next(ch))
@@ -115,7 +154,7 @@ func ExampleAccumulate() {
 	// Output: 1361015
 }
 
-func ExampleAccumulateWithStart() {
+func ExampleAccumulate_withStart() {
 	arr := []int{1, 2, 3, 4, 5}
 	ch := Accumulate(arr, "", 100)
 	for value := range ch {
@@ -124,7 +163,7 @@ func ExampleAccumulateWithStart() {
 	// Output: 100:101:103:106:110:115:
 }
 
-func ExampleAccumulateMultiply() {
+func ExampleAccumulate_multiply() {
 	arr := []int{1, 2, 3, 4, 5}
 	ch := Accumulate(arr, "multiply", 0)
 	for value := range ch {
@@ -133,7 +172,7 @@ func ExampleAccumulateMultiply() {
 	// Output: 1:2:6:24:120:
 }
 
-func ExampleAccumulateMultiplyWithStart() {
+func ExampleAccumulate_multiplyWithStart() {
 	arr := []int{1, 2, 3, 4, 5}
 	ch := Accumulate(arr, "multiply", 100)
 	for value := range ch {
@@ -151,7 +190,7 @@ func ExampleTee() {
 	// Output: AB:CD:EF:GH:IJ:KL:MN:OP:Q:
 }
 
-func ExampleTeeArray() {
+func ExampleTee_array() {
 	param := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
 	ch := Tee(param, 2)
 	for value := range ch {
@@ -169,6 +208,17 @@ func ExamplePairwise() {
 	// Output: AB:CD:EF:GH:IJ:KL:P:
 }
 
+func TestPairwiseStop(t *testing.T) {
+	before := runningProducers()
+	for i := 0; i < 10; i++ {
+		ch := Pairwise("ABCDEFGH")
+		<-ch
+		Stop(ch)
+		ensureClosed(t, ch)
+	}
+	ensureProducers(t, before)
+}
+
 func generateRandomString(stringLength int) (result string) {
 	var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
@@ -195,3 +245,2960 @@ func TestCompressString(t *testing.T) {
 	}
 	// Output: A:C:F:
 }
+
+func ExampleTranspose() {
+	matrix := [][]int{{1, 2, 3}, {4, 5, 6}}
+	result, err := Transpose(matrix)
+	fmt.Printf("%v %v", result, err)
+	// Output: [[1 4] [2 5] [3 6]] <nil>
+}
+
+func TestTransposeRagged(t *testing.T) {
+	matrix := [][]int{{1, 2, 3}, {4, 5}}
+	if _, err := Transpose(matrix); err != ErrUnequalLengths {
+		t.Error("expected ErrUnequalLengths, got: ", err)
+	}
+}
+
+func ExampleTransposeIter() {
+	matrix := [][]int{{1, 2, 3}, {4, 5, 6}}
+	for value := range TransposeIter(matrix) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 4][2 5][3 6]
+}
+
+func ExampleTransposeIter_ragged() {
+	matrix := [][]int{{1, 2, 3}, {4, 5}}
+	for value := range TransposeIter(matrix) {
+		fmt.Printf("%v", value)
+	}
+	// Output: all parameters must be of the same length
+}
+
+func ExampleFilterMap() {
+	data := []string{"1", "two", "3", "4.5", "5"}
+	ch := FilterMap(data, func(s string) (int, bool) {
+		n, err := strconv.Atoi(s)
+		return n, err == nil
+	})
+	for value := range ch {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1:3:5:
+}
+
+func ExampleMaxIter() {
+	ch := Iter([]int{3, 9, 1, 7})
+	fmt.Println(MaxIter(ch))
+	// Output: 9 true <nil>
+}
+
+func ExampleMinIter() {
+	ch := Iter([]float64{2.5, -1.5, 4, 0})
+	fmt.Println(MinIter(ch))
+	// Output: -1.5 true <nil>
+}
+
+func TestMaxIterEmpty(t *testing.T) {
+	if _, ok, err := MaxIter(Iter([]int{})); ok || err != nil {
+		t.Error("expected nothing from an empty iterator, got: ", ok, err)
+	}
+}
+
+func TestMinIterMixedTypes(t *testing.T) {
+	ch := Iter([]any{1, 2.5, 3})
+	if _, _, err := MinIter(ch); err != ErrMixedTypes {
+		t.Error("expected ErrMixedTypes, got: ", err)
+	}
+	unbounded := NewGenerator(func(yield func(any) bool) {
+		for i := 0; yield(i); i++ {
+			if i == 2 && !yield("two") {
+				return
+			}
+		}
+	})
+	if _, _, err := MaxIter(unbounded); err != ErrMixedTypes {
+		t.Error("expected ErrMixedTypes, got: ", err)
+	}
+	ensureClosed(t, unbounded)
+}
+
+type groupReduceItem struct {
+	category string
+	amount   int
+}
+
+func ExampleGroupReduce() {
+	items := []groupReduceItem{{"food", 5}, {"rent", 100}, {"food", 7}, {"fun", 20}, {"food", 3}}
+	sums := GroupReduce(items, func(i groupReduceItem) string { return i.category },
+		func(acc int, i groupReduceItem) int { return acc + i.amount }, 0)
+	fmt.Println(sums)
+	// Output: map[food:15 fun:20 rent:100]
+}
+
+func ExampleGroupReduce_count() {
+	words := []string{"apple", "avocado", "banana", "blueberry", "cherry", "apricot"}
+	counts := GroupReduce(words, func(w string) byte { return w[0] },
+		func(acc int, _ string) int { return acc + 1 }, 0)
+	fmt.Println(counts['a'], counts['b'], counts['c'])
+	// Output: 3 2 1
+}
+
+// ensureClosed fails t if ch is not closed shortly after its producer has been stopped
+func ensureClosed(t *testing.T, ch Iterator) {
+	t.Helper()
+	closed := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("producer did not stop")
+	}
+}
+
+func ExampleTake() {
+	ch := Take(Iter([]int{1, 2, 3, 4, 5}), 3)
+	for value := range ch {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1:2:3:
+}
+
+func TestStop(t *testing.T) {
+	ch := Primes()
+	Next(ch)
+	Stop(ch)
+	Stop(ch)
+	ensureClosed(t, ch)
+}
+
+func ExamplePrimes() {
+	for value := range Take(Primes(), 10) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 2:3:5:7:11:13:17:19:23:29:
+}
+
+func TestPrimesStopsEarly(t *testing.T) {
+	primes := Primes()
+	for value := range primes {
+		if value.(int) > 100 {
+			break
+		}
+	}
+	Stop(primes)
+	ensureClosed(t, primes)
+}
+
+func ExampleGenerate() {
+	for value := range Take(Generate(1, func(n int) int { return n * 3 }), 5) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1:3:9:27:81:
+}
+
+func ExampleGenerateState() {
+	fibonacci := GenerateState([2]int{0, 1}, func(s [2]int) ([2]int, int) {
+		return [2]int{s[1], s[0] + s[1]}, s[0]
+	})
+	for value := range Take(fibonacci, 10) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 0:1:1:2:3:5:8:13:21:34:
+}
+
+func ExampleFibonacci() {
+	for value := range Take(Fibonacci(), 10) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 0:1:1:2:3:5:8:13:21:34:
+}
+
+func TestFibonacciStops(t *testing.T) {
+	ch := Fibonacci()
+	Next(ch)
+	Stop(ch)
+	ensureClosed(t, ch)
+}
+
+func TestFibonacciBig(t *testing.T) {
+	expected := "12200160415121876738"
+	var value any
+	for value = range Take(FibonacciBig(), 94) {
+	}
+	if value.(*big.Int).String() != expected {
+		t.Error("F(93) not expected number, got: ", value, "expected :", expected)
+	}
+}
+
+func TestMovingAverage(t *testing.T) {
+	data := []int{4, 8, 15, 16, 23, 42, 7}
+	window := 3
+	var expected []float64
+	for i := 0; i+window <= len(data); i++ {
+		sum := 0
+		for _, element := range data[i : i+window] {
+			sum += element
+		}
+		expected = append(expected, float64(sum)/float64(window))
+	}
+	counter := 0
+	for value := range MovingAverage(data, window) {
+		if math.Abs(value.(float64)-expected[counter]) > 1e-9 {
+			t.Error("window", counter, "got: ", value, "expected :", expected[counter])
+		}
+		counter++
+	}
+	if counter != len(expected) {
+		t.Error("counter not expected number, got: ", counter, "expected :", len(expected))
+	}
+}
+
+func ExampleMovingAverage() {
+	for value := range MovingAverage([]float64{1, 2, 3, 4, 5}, 2) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1.5:2.5:3.5:4.5:
+}
+
+func TestMaskRoundTrip(t *testing.T) {
+	original := []string{"A", "", "C", "", "", "F"}
+	selector := []bool{true, false, true, false, false, true}
+	var compressed []string
+	for value := range Compress(original, selector) {
+		compressed = append(compressed, value.(string))
+	}
+	counter := 0
+	for value := range Mask(compressed, selector, "") {
+		if value != original[counter] {
+			t.Error("position", counter, "got: ", value, "expected :", original[counter])
+		}
+		counter++
+	}
+	if counter != len(original) {
+		t.Error("counter not expected number, got: ", counter, "expected :", len(original))
+	}
+}
+
+func ExampleMask() {
+	for value := range Mask([]int{1, 2}, []bool{false, true, true, false, true}, 0) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 0:1:2:0:
+}
+
+func TestTimeout(t *testing.T) {
+	slow := make(Iterator)
+	go func() {
+		slow <- 1
+		slow <- 2
+		time.Sleep(200 * time.Millisecond)
+		slow <- 3
+		close(slow)
+	}()
+	var values []any
+	for value := range Timeout(slow, 50*time.Millisecond) {
+		values = append(values, value)
+	}
+	if len(values) != 3 || values[0] != 1 || values[1] != 2 || values[2] != ErrTimeout {
+		t.Error("expected 1, 2 then ErrTimeout, got: ", values)
+	}
+}
+
+func TestTimeoutPassesClose(t *testing.T) {
+	counter := 0
+	for value := range Timeout(Iter([]int{1, 2, 3}), time.Second) {
+		if value == ErrTimeout {
+			t.Error("unexpected timeout")
+		}
+		counter++
+	}
+	if counter != 3 {
+		t.Error("counter not expected number, got: ", counter, "expected :", 3)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !Equal(Iter([]int{1, 2, 3}), Chain([]int{1}, []int{2, 3})) {
+		t.Error("expected equal iterators")
+	}
+	if Equal(Iter([]int{1, 2, 3}), Iter([]int{1, 2})) {
+		t.Error("expected iterators of differing length to be unequal")
+	}
+	if Equal(Iter([]int{1, 2, 3}), Iter([]int{1, 5, 3})) {
+		t.Error("expected iterators with differing values to be unequal")
+	}
+}
+
+func TestEqualFunc(t *testing.T) {
+	approx := func(x, y any) bool { return math.Abs(x.(float64)-y.(float64)) < 1e-9 }
+	if !EqualFunc(Iter([]float64{0.3, 0.6}), Iter([]float64{0.1 + 0.2, 0.2 + 0.4}), approx) {
+		t.Error("expected approximately equal iterators")
+	}
+}
+
+func TestKeysValues(t *testing.T) {
+	m := map[string]int{"a": 1, "b": 2, "c": 3}
+	var keys []string
+	for value := range Keys(IterMap(m)) {
+		keys = append(keys, value.(string))
+	}
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[a b c]" {
+		t.Error("keys not expected, got: ", keys)
+	}
+	var values []int
+	for value := range Values(IterMap(m)) {
+		values = append(values, value.(int))
+	}
+	sort.Ints(values)
+	if fmt.Sprint(values) != "[1 2 3]" {
+		t.Error("values not expected, got: ", values)
+	}
+}
+
+func TestKeysNotEntry(t *testing.T) {
+	var last any
+	for value := range Keys(Iter([]int{1, 2})) {
+		last = value
+	}
+	if err, ok := last.(error); !ok || !errors.Is(err, ErrUnexpectedType) {
+		t.Error("expected ErrUnexpectedType, got: ", last)
+	}
+}
+
+func ExampleAccumulateChecked() {
+	arr := []int{1, 2, 3, 4, 5}
+	for value := range AccumulateChecked(arr, "multiply", 100) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 100:101:102:106:124:220:
+}
+
+func TestAccumulateCheckedOverflow(t *testing.T) {
+	arr := []int{math.MaxInt / 2, 2, 2}
+	var values []any
+	for value := range AccumulateChecked(arr, "multiply", 0) {
+		values = append(values, value)
+	}
+	if len(values) != 3 || values[2] != ErrOverflow {
+		t.Error("expected ErrOverflow after two values, got: ", values)
+	}
+}
+
+func TestAccumulateCheckedPower(t *testing.T) {
+	var last any
+	for value := range AccumulateChecked([]int{3, 40}, "power", 0) {
+		last = value
+	}
+	if last != ErrOverflow {
+		t.Error("expected ErrOverflow, got: ", last)
+	}
+	for _, test := range []struct {
+		base, exponent, expected int
+	}{{2, 62, 1 << 62}, {-2, 63, math.MinInt}, {-3, 3, -27}, {7, 0, 1}, {0, 1 << 40, 0}, {1, 1 << 40, 1}, {-1, 1<<40 + 1, -1}} {
+		result, err := checkedPower(test.base, test.exponent)
+		if err != nil || result != test.expected {
+			t.Error("expected ", test.base, "**", test.exponent, " to be ", test.expected, ", got: ", result, err)
+		}
+	}
+	values := Collect[int](AccumulateChecked([]int{1, 1 << 40}, "power", 0))
+	if !slices.Equal(values, []int{1, 1}) {
+		t.Error("expected 1 1, got: ", values)
+	}
+	if _, err := checkedPower(2, 63); err != ErrOverflow {
+		t.Error("expected ErrOverflow, got: ", err)
+	}
+	var sent []any
+	for value := range AccumulateChecked([]int{0, -1}, "power", 0) {
+		sent = append(sent, value)
+	}
+	if len(sent) != 2 || !errors.Is(sent[1].(error), ErrInvalidOperator) {
+		t.Error("expected ErrInvalidOperator for a negative exponent, got: ", sent)
+	}
+}
+
+func ExampleRepeatSlice() {
+	for value := range RepeatSlice([]int{1, 2}, 3) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 121212
+}
+
+func TestRepeatSliceZero(t *testing.T) {
+	for value := range RepeatSlice([]int{1, 2}, 0) {
+		t.Error("expected nothing, got: ", value)
+	}
+}
+
+func ExampleRepeatSlice_forever() {
+	for value := range Take(RepeatSlice([]string{"a", "b"}, -1), 5) {
+		fmt.Printf("%v", value)
+	}
+	// Output: ababa
+}
+
+func ExampleIterate() {
+	collatz := func(n int) int {
+		if n%2 == 0 {
+			return n / 2
+		}
+		return 3*n + 1
+	}
+	for value := range Iterate(6, collatz, func(n int) bool { return n == 1 }) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 6:3:10:5:16:8:4:2:1:
+}
+
+func ExampleUnique() {
+	fmt.Println(Unique([]string{"a", "b", "a", "c"}))
+	// Output: [a b c]
+}
+
+func ExampleUniqueLast() {
+	fmt.Println(UniqueLast([]string{"a", "b", "a", "c"}))
+	// Output: [b a c]
+}
+
+func ExampleBucket() {
+	data := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	fmt.Println(Bucket(data, func(n int) int { return n % 3 }, 3))
+	// Output: [[0 3 6] [1 4 7] [2 5]]
+}
+
+func ExampleBucket_clamped() {
+	data := []int{-5, 1, 50}
+	fmt.Println(Bucket(data, func(n int) int { return n / 10 }, 3))
+	// Output: [[-5 1] [] [50]]
+}
+
+func ExampleInterleave() {
+	for value := range Interleave([]int{1, 2, 3}, []int{4, 5}, []int{6, 7, 8}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 146257
+}
+
+func ExampleInterleaveLongest() {
+	for value := range InterleaveLongest([]int{1, 2, 3}, []int{4, 5}, []int{6, 7, 8}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 14625738
+}
+
+func ExampleStatefulMap() {
+	data := []int{3, 4, 5, 1, 9, 2, 8}
+	ch := StatefulMap(data, 0, func(sum, x int) (int, int, bool) {
+		sum += x
+		if sum > 10 {
+			return 0, sum, true
+		}
+		return sum, 0, false
+	})
+	for value := range ch {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 12:12:
+}
+
+func TestBroadcast(t *testing.T) {
+	outputs := Broadcast(Iter([]int{1, 2, 3, 4}), 2)
+	results := make([][]any, len(outputs))
+	var wg sync.WaitGroup
+	for i, output := range outputs {
+		wg.Add(1)
+		go func(i int, output Iterator) {
+			defer wg.Done()
+			for value := range output {
+				results[i] = append(results[i], value)
+			}
+		}(i, output)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if fmt.Sprint(result) != "[1 2 3 4]" {
+			t.Error("consumer", i, "got: ", result)
+		}
+	}
+}
+
+func TestBroadcastNoOutputs(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		ch := Iter([]int{1})
+		if outputs := Broadcast(ch, n); len(outputs) != 0 {
+			t.Error("expected no outputs for ", n, ", got: ", outputs)
+		}
+		Stop(ch)
+	}
+}
+
+func ExampleCoalesce() {
+	intervals := [][2]int{{1, 3}, {2, 6}, {8, 10}, {9, 12}, {15, 18}}
+	ch := Coalesce(intervals, func(acc, x [2]int) ([2]int, bool) {
+		if x[0] > acc[1] {
+			return acc, false
+		}
+		if x[1] > acc[1] {
+			acc[1] = x[1]
+		}
+		return acc, true
+	})
+	for value := range ch {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 6][8 12][15 18]
+}
+
+func ExampleHistogram() {
+	counts, edges := Histogram([]float64{0, 1, 2, 3, 4, 5, 6, 7, 8}, 4)
+	fmt.Println(counts, edges)
+	// Output: [2 2 2 3] [0 2 4 6 8]
+}
+
+func ExampleHistogram_allEqual() {
+	counts, edges := Histogram([]int{5, 5, 5}, 3)
+	fmt.Println(counts, edges)
+	// Output: [3 0 0] [5 5 5 5]
+}
+
+func TestHistogramEmpty(t *testing.T) {
+	counts, edges := Histogram([]int{}, 3)
+	if len(counts) != 0 || len(edges) != 0 {
+		t.Error("expected empty results, got: ", counts, edges)
+	}
+}
+
+func ExampleConcat() {
+	for value := range Concat[int]([]int{1, 2}, Iter([]int{3, 4}), []int{5}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 12345
+}
+
+func TestConcatStopsLaterParts(t *testing.T) {
+	before := runningProducers()
+	later := Count(0, 1)
+	for range Take(Concat[int]([]int{1, 2, 3}, later), 1) {
+	}
+	ensureClosed(t, later)
+	afterInvalid := Count(0, 1)
+	for range Concat[int]([]int{1}, "x", afterInvalid) {
+	}
+	ensureClosed(t, afterInvalid)
+	ensureProducers(t, before)
+}
+
+func TestConcatInvalidPart(t *testing.T) {
+	var values []any
+	for value := range Concat[int]([]int{1}, "two") {
+		values = append(values, value)
+	}
+	if len(values) != 2 || !errors.Is(values[1].(error), ErrUnexpectedType) {
+		t.Error("expected 1 then ErrUnexpectedType, got: ", values)
+	}
+}
+
+func ExampleFoldLeft() {
+	// ((0 - 1) - 2) - 3
+	fmt.Println(FoldLeft([]int{1, 2, 3}, func(acc, x int) int { return acc - x }, 0))
+	// Output: -6
+}
+
+func ExampleFoldRight() {
+	// 1 - (2 - (3 - 0))
+	fmt.Println(FoldRight([]int{1, 2, 3}, func(x, acc int) int { return x - acc }, 0))
+	// Output: 2
+}
+
+type person struct {
+	Name string
+	Age  int
+}
+
+func ExampleZipWith() {
+	names := []string{"Ann", "Bob", "Cat"}
+	ages := []int{31, 42}
+	ch := ZipWith(names, ages, func(name string, age int) person { return person{name, age} })
+	for value := range ch {
+		fmt.Printf("%+v", value)
+	}
+	// Output: {Name:Ann Age:31}{Name:Bob Age:42}
+}
+
+func ExampleZipWithIter() {
+	for value := range ZipWithIter(Iter([]int{1, 2, 3}), Iter([]string{"a", "b"}), func(x, y any) any { return fmt.Sprint(x, y) }) {
+		fmt.Printf("%v ", value)
+	}
+	// Output: 1a 2b
+}
+
+func TestZipWithIterStopsBoth(t *testing.T) {
+	before := runningProducers()
+	a, b := Count(0, 1), Count(100, 1)
+	var got []any
+	for value := range ZipWithIter(Take(a, 3), b, func(x, y any) any { return x.(int) + y.(int) }) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{100, 102, 104}) {
+		t.Error("expected 100 102 104, got: ", got)
+	}
+	ensureClosed(t, a)
+	ensureClosed(t, b)
+	ensureProducers(t, before)
+}
+func ExampleDifference() {
+	for value := range Difference([]int{5, 1, 4, 2, 3}, []int{4, 3, 6}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 512
+}
+
+func ExampleIntersection() {
+	for value := range Intersection([]int{5, 1, 4, 2, 3}, []int{4, 3, 6}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 43
+}
+
+func ExampleUnion() {
+	for value := range Union([]int{5, 1, 5, 4}, []int{4, 3, 6, 1}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 51436
+}
+
+func ExampleSortedUnion() {
+	for value := range SortedUnion([]int{1, 2, 4}, []int{2, 3, 4}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 1234
+}
+
+func TestSortedUnion(t *testing.T) {
+	var got []any
+	for value := range SortedUnion([]int{1, 1, 5, 7}, []int{}) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{1, 5, 7}) {
+		t.Error("expected duplicates within one input to be dropped, got: ", got)
+	}
+	got = nil
+	for value := range SortedUnion([]string{}, []string{"a", "b", "b"}) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{"a", "b"}) {
+		t.Error("expected a b, got: ", got)
+	}
+}
+
+func ExampleSampleEvery() {
+	data := []string{"A1", "B1", "A2", "A3", "B2", "B3", "A4", "B4", "B5"}
+	for value := range SampleEvery(data, func(s string) byte { return s[0] }, 2) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: A1:B1:A3:B3:B5:
+}
+
+func ExampleSampleEvery_invalid() {
+	for value := range SampleEvery([]int{1}, func(n int) int { return n }, 0) {
+		fmt.Printf("%v", value)
+	}
+	// Output: size must be greater than zero
+}
+
+func ExampleRollingFold() {
+	sum := func(window []int) int {
+		total := 0
+		for _, x := range window {
+			total += x
+		}
+		return total
+	}
+	for value := range RollingFold([]int{1, 2, 3, 4, 5}, 3, sum) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 6:9:12:
+}
+
+func ExampleRollingFold_median() {
+	median := func(window []int) int {
+		sort.Ints(window)
+		return window[len(window)/2]
+	}
+	data := []int{5, 1, 9, 3, 7, 2}
+	for value := range RollingFold(data, 3, median) {
+		fmt.Printf("%v:", value)
+	}
+	fmt.Println(data)
+	// Output: 5:3:7:3:[5 1 9 3 7 2]
+}
+
+func ExampleSkipNil() {
+	var missing *int
+	var noSlice []int
+	for value := range SkipNil(Iter([]any{1, nil, "a", missing, noSlice, 0, []int{2}})) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1:a:0:[2]:
+}
+
+func ExamplePipeline() {
+	result, err := NewPipeline(Count(1, 1)).
+		Filter(func(x any) bool { return x.(int)%2 == 0 }).
+		Map(func(x any) (any, error) { return x.(int) * x.(int), nil }).
+		Take(4).
+		Collect()
+	fmt.Println(result, err)
+	// Output: [4 16 36 64] <nil>
+}
+
+func TestPipelineError(t *testing.T) {
+	errOdd := errors.New("odd number")
+	source := Iter([]int{2, 4, 5, 6})
+	seen := 0
+	err := NewPipeline(source).
+		Map(func(x any) (any, error) {
+			if x.(int)%2 != 0 {
+				return nil, errOdd
+			}
+			return x, nil
+		}).
+		ForEach(func(any) { seen++ })
+	if err != errOdd || seen != 2 {
+		t.Error("expected errOdd after 2 elements, got: ", err, seen)
+	}
+	ensureClosed(t, source)
+}
+
+func ExamplePipeline_Reduce() {
+	sum, err := NewPipeline(Iter([]int{1, 2, 3, 4})).
+		Reduce(func(acc, x any) any { return acc.(int) + x.(int) }, 0)
+	fmt.Println(sum, err)
+	// Output: 10 <nil>
+}
+
+func ExampleDedupBy() {
+	words := []string{"Go", "go", "GO", "rust", "Go", "zig", "ZIG"}
+	for value := range DedupBy(words, strings.EqualFold) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: Go:rust:Go:zig:
+}
+
+func ExamplePrefixSums() {
+	fmt.Println(PrefixSums([]int{1, 2, 3}), PrefixSums([]int{}))
+	// Output: [1 3 6] []
+}
+
+func ExamplePrefixProducts() {
+	fmt.Println(PrefixProducts([]float64{1, 2, 3, 0.5}))
+	// Output: [1 2 6 3]
+}
+
+func ExampleUpdateMap() {
+	prices := map[string]int{"apple": 3, "melon": 12, "pear": 4, "grape": 9}
+	expensive := NewPipeline(IterMap(prices)).
+		Filter(func(x any) bool { return x.(MapEntry[string, int]).Value > 5 }).
+		Iter()
+	dst := map[string]int{"kiwi": 7}
+	err := UpdateMap(dst, expensive)
+	fmt.Println(dst, err)
+	// Output: map[grape:9 kiwi:7 melon:12] <nil>
+}
+
+func TestUpdateMapWrongType(t *testing.T) {
+	err := UpdateMap(map[string]int{}, IterMap(map[string]string{"a": "b"}))
+	if !errors.Is(err, ErrUnexpectedType) {
+		t.Error("expected ErrUnexpectedType, got: ", err)
+	}
+}
+
+func ExampleGroupBySorted() {
+	records := []groupReduceItem{{"rent", 100}, {"food", 5}, {"fun", 20}, {"food", 7}, {"rent", 90}}
+	for value := range GroupBySorted(records, func(i groupReduceItem) string { return i.category }) {
+		fmt.Printf("%v\n", value)
+	}
+	// Output:
+	// {food [{food 5} {food 7}]}
+	// {fun [{fun 20}]}
+	// {rent [{rent 100} {rent 90}]}
+}
+
+func TestGroupBySortedNaN(t *testing.T) {
+	nan := math.NaN()
+	var keys []float64
+	var sizes []int
+	for value := range Take(GroupBySorted([]float64{1, nan, 2, nan}, func(x float64) float64 { return x }), 10) {
+		group := value.(Group[float64, float64])
+		keys = append(keys, group.Key)
+		sizes = append(sizes, len(group.Items))
+	}
+	if len(keys) != 3 || !math.IsNaN(keys[0]) || keys[1] != 1 || keys[2] != 2 || !slices.Equal(sizes, []int{2, 1, 1}) {
+		t.Error("expected NaN keys to form one group sorted first, got: ", keys, sizes)
+	}
+}
+
+func ExampleGroupAggSorted() {
+	items := []groupReduceItem{{"fruit", 4}, {"dairy", 3}, {"fruit", 2}, {"bakery", 5}, {"dairy", 6}}
+	average := func(group []groupReduceItem) float64 {
+		total := 0
+		for _, item := range group {
+			total += item.amount
+		}
+		return float64(total) / float64(len(group))
+	}
+	for value := range GroupAggSorted(items, func(i groupReduceItem) string { return i.category }, average) {
+		entry := value.(MapEntry[string, float64])
+		fmt.Printf("%v:%v ", entry.Key, entry.Value)
+	}
+	// Output: bakery:5 dairy:4.5 fruit:3
+}
+
+func TestBatchWeighted(t *testing.T) {
+	items := []int{3, 4, 2, 9, 1, 1, 5, 12, 6}
+	maxWeight := 8
+	identity := func(n int) int { return n }
+	var total []int
+	for value := range BatchWeighted(items, identity, maxWeight) {
+		batch := value.([]int)
+		sum := 0
+		for _, item := range batch {
+			sum += item
+		}
+		if sum > maxWeight && len(batch) != 1 {
+			t.Error("batch over the limit: ", batch)
+		}
+		total = append(total, batch...)
+	}
+	if fmt.Sprint(total) != fmt.Sprint(items) {
+		t.Error("batches lost elements, got: ", total)
+	}
+}
+
+func ExampleBatchWeighted() {
+	words := []string{"go", "is", "fun", "extraordinarily", "so", "try", "it"}
+	length := func(s string) int { return len(s) }
+	for value := range BatchWeighted(words, length, 6) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [go is][fun][extraordinarily][so try][it]
+}
+
+func ExampleCycleN() {
+	for value := range CycleN([]int{1, 2}, 5) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 12121
+}
+
+func TestCycleNEmpty(t *testing.T) {
+	for value := range CycleN([]int{}, 5) {
+		t.Error("expected nothing, got: ", value)
+	}
+}
+
+func ExampleSplitIter() {
+	for value := range SplitIter("a,b,,c,", ",") {
+		fmt.Printf("%q", value)
+	}
+	// Output: "a""b""""c"""
+}
+
+func ExampleSplitIter_runes() {
+	for value := range SplitIter("héllo", "") {
+		fmt.Printf("%v:", value)
+	}
+	// Output: h:é:l:l:o:
+}
+
+func TestSplitIterEarlyStop(t *testing.T) {
+	source := SplitIter(strings.Repeat("field,", 100000), ",")
+	counter := 0
+	for range Take(source, 2) {
+		counter++
+	}
+	if counter != 2 {
+		t.Error("counter not expected number, got: ", counter, "expected :", 2)
+	}
+	ensureClosed(t, source)
+}
+
+// runningProducers reports how many Iterators created by produce have not yet finished
+func runningProducers() int {
+	stopsMu.Lock()
+	defer stopsMu.Unlock()
+	return len(stops)
+}
+
+// ensureProducers fails t if the number of running producers does not settle back to expected
+func ensureProducers(t *testing.T, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runningProducers() != expected {
+		if time.Now().After(deadline) {
+			t.Error("running producers not expected number, got: ", runningProducers(), "expected :", expected)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func ExampleZipCount() {
+	for value := range ZipCount([]string{"a", "b", "c"}, 1) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 a][2 b][3 c]
+}
+
+func TestZipCountStopsCount(t *testing.T) {
+	before := runningProducers()
+	for range ZipCount([]int{7, 8, 9}, 0) {
+	}
+	ensureProducers(t, before)
+}
+
+func ExampleProductIndices() {
+	for value := range ProductIndices(2, 2) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [0 0][0 1][1 0][1 1]
+}
+
+func TestProductIndicesLexicographic(t *testing.T) {
+	var previous []int
+	counter := 0
+	for value := range ProductIndices(2, 3, 4) {
+		current := value.([]int)
+		if previous != nil && slices.Compare(previous, current) >= 0 {
+			t.Error("not in lexicographic order: ", previous, current)
+		}
+		previous = current
+		counter++
+	}
+	if counter != 24 {
+		t.Error("counter not expected number, got: ", counter, "expected :", 24)
+	}
+}
+
+func ExampleProduct() {
+	for value := range Product([]string{"a", "b"}, []string{"x", "y", "z"}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [a x][a y][a z][b x][b y][b z]
+}
+
+func ExampleDropLast() {
+	for value := range DropLast([]int{1, 2, 3, 4, 5}, 2) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 123
+}
+
+func ExampleDropLastIter() {
+	for value := range DropLastIter(Take(Count(1, 1), 5), 2) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 123
+}
+
+func ExampleTakeLastWhile() {
+	fmt.Println(TakeLastWhile([]int{1, 8, 3, 4, 6}, func(n int) bool { return n%2 == 0 }))
+	// Output: [4 6]
+}
+
+func TestClone(t *testing.T) {
+	first, second := Clone(Iter([]int{1, 2, 3, 4, 5}))
+	var a, b []any
+	for value := range first {
+		a = append(a, value)
+	}
+	for value := range second {
+		b = append(b, value)
+	}
+	if fmt.Sprint(a) != "[1 2 3 4 5]" || fmt.Sprint(b) != fmt.Sprint(a) {
+		t.Error("clones differ, got: ", a, b)
+	}
+}
+
+func ExampleLinspace() {
+	for value := range Linspace(0, 1, 5) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 0:0.25:0.5:0.75:1:
+}
+
+func TestLinspaceEdges(t *testing.T) {
+	var values []any
+	for value := range Linspace(2, 9, 1) {
+		values = append(values, value)
+	}
+	if len(values) != 1 || values[0] != 2.0 {
+		t.Error("expected only start, got: ", values)
+	}
+	for value := range Linspace(2, 9, 0) {
+		t.Error("expected nothing, got: ", value)
+	}
+}
+
+func ExampleRecords() {
+	header := []string{"name", "age"}
+	rows := [][]string{{"Ann", "31"}, {"Bob", "42"}}
+	for value := range Records(header, rows) {
+		fmt.Println(value)
+	}
+	// Output:
+	// map[age:31 name:Ann]
+	// map[age:42 name:Bob]
+}
+
+func TestRecordsMismatchedWidth(t *testing.T) {
+	var values []any
+	for value := range Records([]string{"name", "age"}, [][]string{{"Ann", "31"}, {"Bob"}}) {
+		values = append(values, value)
+	}
+	if len(values) != 2 || !errors.Is(values[1].(error), ErrUnequalLengths) {
+		t.Error("expected a record then ErrUnequalLengths, got: ", values)
+	}
+}
+
+func ExampleGroupBy() {
+	for value := range GroupBy([]int{1, 3, 2, 4, 6, 5}, func(n int) bool { return n%2 == 0 }) {
+		fmt.Printf("%v", value)
+	}
+	// Output: {false [1 3]}{true [2 4 6]}{false [5]}
+}
+
+func TestUngroupRoundTrip(t *testing.T) {
+	words := []string{"apple", "avocado", "banana", "cherry", "cranberry"}
+	first := func(s string) byte { return s[0] }
+	counter := 0
+	for value := range Ungroup[byte, string](GroupBy(words, first)) {
+		e := value.(MapEntry[byte, string])
+		if e.Value != words[counter] || e.Key != first(words[counter]) {
+			t.Error("pair", counter, "got: ", e)
+		}
+		counter++
+	}
+	if counter != len(words) {
+		t.Error("counter not expected number, got: ", counter, "expected :", len(words))
+	}
+}
+
+func TestUngroupNotGroup(t *testing.T) {
+	var last any
+	for value := range Ungroup[int, int](Iter([]int{1})) {
+		last = value
+	}
+	if err, ok := last.(error); !ok || !errors.Is(err, ErrUnexpectedType) {
+		t.Error("expected ErrUnexpectedType, got: ", last)
+	}
+}
+
+func TestDistinctCount(t *testing.T) {
+	data := []int{1, 2, 1, 3, 3, 3, 4, 1, 2, 2}
+	window := 4
+	counter := 0
+	for value := range DistinctCount(data, window) {
+		expected := len(Unique(data[counter : counter+window]))
+		if value != expected {
+			t.Error("window", counter, "got: ", value, "expected :", expected)
+		}
+		counter++
+	}
+	if counter != len(data)-window+1 {
+		t.Error("counter not expected number, got: ", counter, "expected :", len(data)-window+1)
+	}
+}
+
+func ExampleDistinctCount_invalid() {
+	for value := range DistinctCount([]int{1, 2}, 0) {
+		fmt.Printf("%v", value)
+	}
+	// Output: size must be greater than zero
+}
+
+func ExampleZipStruct() {
+	names := []any{"Ann", "Bob"}
+	ages := []any{31, 42}
+	for value := range ZipStruct[person](names, ages) {
+		fmt.Printf("%+v", value)
+	}
+	// Output: {Name:Ann Age:31}{Name:Bob Age:42}
+}
+
+func TestZipStructFieldCount(t *testing.T) {
+	var last any
+	for value := range ZipStruct[person]([]any{"Ann"}) {
+		last = value
+	}
+	if err, ok := last.(error); !ok || !errors.Is(err, ErrFieldCount) {
+		t.Error("expected ErrFieldCount, got: ", last)
+	}
+}
+
+func ExampleIterJSONArray() {
+	r := strings.NewReader(`[{"id": 1, "tags": ["a", "b"]}, {"id": 2, "nested": {"ok": true}}, 3]`)
+	for value := range IterJSONArray(r) {
+		fmt.Println(value)
+	}
+	// Output:
+	// map[id:1 tags:[a b]]
+	// map[id:2 nested:map[ok:true]]
+	// 3
+}
+
+func TestIterJSONArrayMalformed(t *testing.T) {
+	var values []any
+	for value := range IterJSONArray(strings.NewReader(`[{"id": 1}, {"id": }]`)) {
+		values = append(values, value)
+	}
+	if len(values) != 2 {
+		t.Fatal("expected one element then an error, got: ", values)
+	}
+	if _, ok := values[1].(error); !ok {
+		t.Error("expected a decoding error, got: ", values[1])
+	}
+}
+
+type customer struct {
+	ID   int
+	Name string
+}
+
+type order struct {
+	CustomerID int
+	Item       string
+}
+
+func ExampleJoinBy() {
+	customers := []customer{{1, "Ann"}, {2, "Bob"}, {3, "Cat"}}
+	orders := []order{{1, "pen"}, {3, "ink"}, {1, "pad"}, {4, "cup"}}
+	ch := JoinBy(customers, orders, func(c customer) int { return c.ID }, func(o order) int { return o.CustomerID })
+	for value := range ch {
+		pair := value.(Pair[customer, order])
+		fmt.Printf("%v:%v ", pair.First.Name, pair.Second.Item)
+	}
+	// Output: Ann:pen Ann:pad Cat:ink
+}
+
+func ExampleCrossJoin() {
+	for value := range CrossJoin([]int{1, 2, 3}, []int{2, 3}, func(a, b int) bool { return a < b }) {
+		pair := value.(Pair[int, int])
+		fmt.Printf("%v<%v ", pair.First, pair.Second)
+	}
+	// Output: 1<2 1<3 2<3
+}
+
+func TestCrossJoinEmpty(t *testing.T) {
+	calls := 0
+	for value := range CrossJoin([]int{1, 2}, []string{}, func(int, string) bool { calls++; return true }) {
+		t.Error("expected no pairs, got: ", value)
+	}
+	if calls != 0 {
+		t.Error("expected pred not to be called, got: ", calls)
+	}
+}
+
+func TestJoinByDuplicateKeys(t *testing.T) {
+	left := []string{"a1", "a2", "b1"}
+	right := []string{"a3", "a4", "c1"}
+	first := func(s string) byte { return s[0] }
+	counter := 0
+	for range JoinBy(left, right, first, first) {
+		counter++
+	}
+	if counter != 4 {
+		t.Error("counter not expected number, got: ", counter, "expected :", 4)
+	}
+}
+
+func TestTeeBufferedBackpressure(t *testing.T) {
+	capacity := 5
+	var produced int64
+	source := make(Iterator)
+	go func() {
+		defer close(source)
+		for i := 0; i < 100; i++ {
+			source <- i
+			atomic.AddInt64(&produced, 1)
+		}
+	}()
+	outputs := TeeBuffered(source, 2, capacity)
+	fastDone := make(chan int)
+	go func() {
+		counter := 0
+		for range outputs[0] {
+			counter++
+		}
+		fastDone <- counter
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// the lagging queue is full, one element is waiting to be sent and one more is held while adding to it
+	if got := atomic.LoadInt64(&produced); got > int64(capacity+2) {
+		t.Error("buffer grew past its capacity, produced: ", got)
+	}
+	slow := 0
+	for range outputs[1] {
+		slow++
+	}
+	if fast := <-fastDone; fast != 100 || slow != 100 {
+		t.Error("consumers did not receive every element, got: ", fast, slow)
+	}
+}
+
+func TestRunningQuantile(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	data := r.Perm(10000)
+	for _, q := range []float64{0.5, 0.9} {
+		var estimate any
+		for estimate = range RunningQuantile(Iter(data), q) {
+		}
+		sorted := append([]int{}, data...)
+		sort.Ints(sorted)
+		exact := float64(sorted[int(q*float64(len(sorted)-1))])
+		if math.Abs(estimate.(float64)-exact) > 0.01*float64(len(data)) {
+			t.Error("quantile", q, "estimate too far off, got: ", estimate, "expected :", exact)
+		}
+	}
+}
+
+func TestRunningQuantileInvalid(t *testing.T) {
+	for value := range RunningQuantile(Iter([]int{1, 2}), 1) {
+		if err, ok := value.(error); !ok || !errors.Is(err, ErrOutOfRange) {
+			t.Error("expected ErrOutOfRange, got: ", value)
+		}
+	}
+}
+
+func ExamplePad() {
+	for value := range Pad([]int{1, 2}, 5, 0, false) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 12000
+}
+
+func ExamplePad_atFront() {
+	for value := range Pad([]int{1, 2}, 5, 0, true) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 00012
+}
+
+func ExamplePad_longEnough() {
+	for value := range Pad([]int{1, 2, 3}, 2, 0, false) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 123
+}
+
+func ExampleZip2() {
+	for value := range Zip2([]string{"a", "b", "c"}, []int{1, 2}) {
+		fmt.Printf("%+v", value)
+	}
+	// Output: {First:a Second:1}{First:b Second:2}
+}
+
+func ExampleEnumerateZip2() {
+	for value := range EnumerateZip2([]string{"a", "b"}, []int{10, 20, 30}) {
+		pair := value.(IndexedPair[string, int])
+		fmt.Println(pair.Index, pair.A, pair.B)
+	}
+	// Output:
+	// 0 a 10
+	// 1 b 20
+}
+
+func TestEnumerateZip2Empty(t *testing.T) {
+	for value := range EnumerateZip2([]int{1, 2}, []bool{}) {
+		t.Error("expected nothing, got: ", value)
+	}
+}
+
+func ExampleZip3() {
+	for value := range Zip3([]string{"a", "b", "c"}, []int{1, 2, 3}, []bool{true, false}) {
+		fmt.Printf("%+v", value)
+	}
+	// Output: {First:a Second:1 Third:true}{First:b Second:2 Third:false}
+}
+
+func ExampleSegment() {
+	for value := range Segment([]int{1, 2, 3, 4, 5}, 2, false) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 2][3 4][5]
+}
+
+func ExampleSegment_overlap() {
+	for value := range Segment([]int{1, 2, 3, 4, 5}, 2, true) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 2][2 3][3 4][4 5]
+}
+
+func TestZipEmpty(t *testing.T) {
+	for value := range Zip[int]() {
+		t.Error("expected nothing, got: ", value)
+	}
+	for value := range Zip([]int{}, []int{}) {
+		t.Error("expected nothing, got: ", value)
+	}
+}
+
+func ExampleCountDown() {
+	for value := range CountDown(10, 0, 2) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 10:8:6:4:2:
+}
+
+func ExampleCountDown_invalidStep() {
+	for value := range CountDown(10, 0, -2) {
+		fmt.Printf("%v", value)
+	}
+	// Output: size must be greater than zero
+}
+
+func ExampleZipLongestIter() {
+	for value := range ZipLongestIter("-", Iter([]int{1, 2}), Iter([]string{"a", "b", "c", "d"})) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 a][2 b][- c][- d]
+}
+
+func ExampleSessionWindow() {
+	timestamps := []int{0, 5, 9, 40, 42, 100, 103, 109}
+	gap := func(prev, cur int) bool { return cur-prev > 30 }
+	for value := range SessionWindow(timestamps, gap) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [0 5 9][40 42][100 103 109]
+}
+
+func ExampleArgMax() {
+	fmt.Println(ArgMax([]int{3, 9, 1, 9, 2}))
+	// Output: 1 9 true
+}
+
+func ExampleArgMin() {
+	fmt.Println(ArgMin([]string{"pear", "fig", "kiwi", "fig"}))
+	// Output: 1 fig true
+}
+
+func TestArgMaxEmpty(t *testing.T) {
+	if index, _, ok := ArgMax([]float64{}); ok || index != -1 {
+		t.Error("expected nothing from an empty slice, got: ", index, ok)
+	}
+}
+
+func TestUnzipRoundTrip(t *testing.T) {
+	columns := [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	outputs := Unzip(Zip(columns...), len(columns))
+	results := make([][]any, len(outputs))
+	var wg sync.WaitGroup
+	for i, output := range outputs {
+		wg.Add(1)
+		go func(i int, output Iterator) {
+			defer wg.Done()
+			for value := range output {
+				results[i] = append(results[i], value)
+			}
+		}(i, output)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if fmt.Sprint(result) != fmt.Sprint(columns[i]) {
+			t.Error("column", i, "got: ", result, "expected :", columns[i])
+		}
+	}
+}
+
+func TestUnzipWrongLength(t *testing.T) {
+	outputs := Unzip(Iter([]any{[]any{1, 2}, []any{3}}), 2)
+	var last any
+	for value := range outputs[0] {
+		last = value
+	}
+	for range outputs[1] {
+	}
+	if err, ok := last.(error); !ok || !errors.Is(err, ErrUnequalLengths) {
+		t.Error("expected ErrUnequalLengths, got: ", last)
+	}
+}
+
+func TestUnzipStop(t *testing.T) {
+	before := runningProducers()
+	tuples := Generate([]any{0, 0}, func(tuple []any) []any { return []any{tuple[0].(int) + 1, tuple[1].(int) - 1} })
+	outputs := Unzip(tuples, 2)
+	for _, output := range outputs {
+		for range Take(output, 3) {
+		}
+	}
+	ensureClosed(t, tuples)
+	ensureProducers(t, before)
+}
+
+func TestIntern(t *testing.T) {
+	words := []string{strings.Repeat("ab", 3), "x", strings.Repeat("a", 1) + "babab"}
+	if unsafe.StringData(words[0]) == unsafe.StringData(words[2]) {
+		t.Fatal("test strings already share their backing")
+	}
+	var interned []string
+	for value := range Intern[string](Iter(words)) {
+		interned = append(interned, value.(string))
+	}
+	if unsafe.StringData(interned[0]) != unsafe.StringData(interned[2]) {
+		t.Error("equal strings do not share their backing after interning")
+	}
+	if fmt.Sprint(interned) != fmt.Sprint(words) {
+		t.Error("interning changed the values, got: ", interned)
+	}
+}
+
+func ExampleZipWithMode() {
+	first := []int{1, 2, 3}
+	second := []int{4, 5}
+	for _, mode := range []ZipMode{ZipShortest, ZipLongest, ZipStrict} {
+		for value := range ZipWithMode(mode, 0, first, second) {
+			fmt.Printf("%v", value)
+		}
+		fmt.Println()
+	}
+	// Output:
+	// [1 4][2 5]
+	// [1 4][2 5][3 0]
+	// [1 4][2 5]all parameters must be of the same length
+}
+
+func ExampleRunes() {
+	for value := range Runes("añ😀z") {
+		fmt.Printf("%q:%T ", value, value)
+	}
+	// Output: 'a':int32 'ñ':int32 '😀':int32 'z':int32
+}
+
+func ExampleGrouper() {
+	for value := range Grouper([]int{1, 2, 3, 4, 5, 6, 7}, 3, 0) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 2 3][4 5 6][7 0 0]
+}
+
+func ExampleFlatMapIter() {
+	ch := FlatMapIter(Iter([]int{1, 2, 3}), func(n any) Iterator { return Repeat(n, n.(int)) })
+	for value := range ch {
+		fmt.Printf("%v", value)
+	}
+	// Output: 122333
+}
+
+func TestFlatMapIterStopsEarly(t *testing.T) {
+	before := runningProducers()
+	ch := FlatMapIter(Count(1, 1), func(n any) Iterator { return Repeat(n, n.(int)) })
+	for range Take(ch, 4) {
+	}
+	ensureProducers(t, before)
+}
+
+func ExampleFirstNDistinct() {
+	ch := Iter([]string{"a", "a", "b", "a", "c", "b", "d", "e"})
+	fmt.Println(FirstNDistinct[string](ch, 3))
+	// Output: [a b c]
+}
+
+func TestFirstNDistinctStops(t *testing.T) {
+	ch := Cycle("abcabc")
+	if result := FirstNDistinct[string](ch, 3); len(result) != 3 {
+		t.Error("expected 3 distinct values, got: ", result)
+	}
+	ensureClosed(t, ch)
+	if result := FirstNDistinct[int](Iter([]int{1, 1, 2}), 5); len(result) != 2 {
+		t.Error("expected 2 distinct values from a short source, got: ", result)
+	}
+}
+
+func ExampleZipForEach() {
+	var sums []int
+	ZipForEach(func(_ int, tuple []int) bool {
+		sums = append(sums, tuple[0]+tuple[1]+tuple[2])
+		return true
+	}, []int{1, 2, 3}, []int{10, 20, 30}, []int{100, 200})
+	fmt.Println(sums)
+	// Output: [111 222]
+}
+
+func TestZipForEachStopsEarly(t *testing.T) {
+	calls := 0
+	ZipForEach(func(index int, _ []string) bool {
+		calls++
+		return index < 1
+	}, []string{"a", "b", "c"}, []string{"d", "e", "f"})
+	if calls != 2 {
+		t.Error("calls not expected number, got: ", calls, "expected :", 2)
+	}
+}
+
+func ExampleTopK() {
+	ch := Iter([]int{5, 1, 9, 3, 7, 9, 2})
+	fmt.Println(TopK(ch, 3, func(a, b int) bool { return a < b }))
+	// Output: [9 9 7]
+}
+
+func TestTopKShortSource(t *testing.T) {
+	result := TopK(Iter([]string{"b", "a"}), 5, func(a, b string) bool { return a < b })
+	if fmt.Sprint(result) != "[b a]" {
+		t.Error("expected every element largest first, got: ", result)
+	}
+}
+
+func ExampleCumulativeMax() {
+	for value := range CumulativeMax([]int{3, 1, 4, 1, 5}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 33445
+}
+
+func ExampleCumulativeMin() {
+	for value := range CumulativeMin([]int{3, 1, 4, 0, 5}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 31100
+}
+
+func ExampleSkipNonFinite() {
+	values := []any{1.5, math.NaN(), math.Inf(1), float32(2), float32(math.Inf(-1)), 3, "x"}
+	for value := range SkipNonFinite(Iter(values)) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1.5:2:3:x:
+}
+
+func ExampleToIndexedMap() {
+	fmt.Println(ToIndexedMap([]string{"a", "b", "c"}))
+	// Output: map[0:a 1:b 2:c]
+}
+
+func TestToIndexedMapIterGaps(t *testing.T) {
+	evens := NewPipeline(ZipCount([]int{4, 7, 8, 1, 6}, 0)).
+		Filter(func(x any) bool { return x.([]any)[1].(int)%2 == 0 }).
+		Iter()
+	result, err := ToIndexedMapIter[int](evens)
+	if err != nil || fmt.Sprint(result) != "map[0:4 2:8 4:6]" {
+		t.Error("expected original positions with gaps, got: ", result, err)
+	}
+}
+
+func ExampleWindowedMap() {
+	spread := func(window []int) int { return window[len(window)-1] - window[0] }
+	for value := range WindowedMap([]int{1, 4, 9, 16, 25}, 3, spread) {
+		fmt.Printf("%+v", value)
+	}
+	// Output: {StartIndex:0 Value:8}{StartIndex:1 Value:12}{StartIndex:2 Value:16}
+}
+
+func ExampleDedupLRU() {
+	// "a" is evicted by "b" and "c" so is sent again, the second "c" is still recent
+	ch := DedupLRU[string](Iter([]string{"a", "a", "b", "c", "a", "c"}), 2)
+	for value := range ch {
+		fmt.Printf("%v", value)
+	}
+	// Output: abca
+}
+
+func TestDedupLRUNoCapacity(t *testing.T) {
+	counter := 0
+	for range DedupLRU[int](Iter([]int{1, 1, 1}), 0) {
+		counter++
+	}
+	if counter != 3 {
+		t.Error("counter not expected number, got: ", counter, "expected :", 3)
+	}
+}
+
+func TestEqualMultiset(t *testing.T) {
+	if !EqualMultiset[int](Iter([]int{1, 2, 2, 3}), Iter([]int{2, 3, 1, 2})) {
+		t.Error("expected equal multisets")
+	}
+	if EqualMultiset[int](Iter([]int{1, 2, 2, 3}), Iter([]int{1, 2, 3, 3})) {
+		t.Error("expected differing multiplicities to be unequal")
+	}
+	if EqualMultiset[int](Iter([]int{1, 2}), Iter([]int{1, 2, 2})) {
+		t.Error("expected differing lengths to be unequal")
+	}
+}
+
+func ExampleAdd() {
+	fmt.Println(Add([]int{1, 2, 3}, []int{10, 20, 30}))
+	// Output: [11 22 33] <nil>
+}
+
+func ExampleSub() {
+	fmt.Println(Sub([]int{1, 2, 3}, []int{10, 20, 30}))
+	// Output: [-9 -18 -27] <nil>
+}
+
+func ExampleMul() {
+	fmt.Println(Mul([]float64{1.5, 2, 3}, []float64{2, 0.5, -1}))
+	// Output: [3 1 -3] <nil>
+}
+
+func ExampleDiv() {
+	fmt.Println(Div([]int{10, 9, 7}, []int{2, 3, 2}))
+	// Output: [5 3 3] <nil>
+}
+
+func TestElementwiseErrors(t *testing.T) {
+	if _, err := Add([]int{1, 2}, []int{1}); err != ErrUnequalLengths {
+		t.Error("expected ErrUnequalLengths, got: ", err)
+	}
+	if _, err := Div([]int{1, 2}, []int{1, 0}); !errors.Is(err, ErrDivideByZero) {
+		t.Error("expected ErrDivideByZero, got: ", err)
+	}
+}
+
+func ExampleMonotonicRuns() {
+	for value := range MonotonicRuns([]int{1, 2, 2, 1, 3}, true) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 2 2][1 3]
+}
+
+func ExampleMonotonicRuns_decreasing() {
+	for value := range MonotonicRuns([]int{1, 2, 2, 1, 3}, false) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1][2 2 1][3]
+}
+
+func TestWeightedSample(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	weights := map[string]float64{"a": 1, "b": 2, "c": 5, "never": 0}
+	items := []string{"a", "b", "c", "never"}
+	weight := func(s string) float64 { return weights[s] }
+	trials := 20000
+	picked := make(map[string]int)
+	for i := 0; i < trials; i++ {
+		for _, item := range WeightedSample(items, weight, 1, r) {
+			picked[item]++
+		}
+	}
+	for _, item := range items {
+		expected := weights[item] / 8
+		if got := float64(picked[item]) / float64(trials); math.Abs(got-expected) > 0.02 {
+			t.Error(item, "picked too often or too rarely, got: ", got, "expected :", expected)
+		}
+	}
+	if len(WeightedSample(items, weight, 10, r)) != 3 {
+		t.Error("expected every item with a positive weight when k is large")
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	source := make(Iterator)
+	go func() {
+		defer close(source)
+		for _, value := range []int{1, 2, 3} {
+			source <- value
+		}
+		time.Sleep(150 * time.Millisecond)
+		for _, value := range []int{4, 5} {
+			source <- value
+		}
+	}()
+	var values []any
+	for value := range Debounce(source, 50*time.Millisecond) {
+		values = append(values, value)
+	}
+	if fmt.Sprint(values) != "[3 5]" {
+		t.Error("expected the last of each burst, got: ", values)
+	}
+}
+
+func ExampleZipInto() {
+	var people []person
+	err := ZipInto(&people, []any{"Ann", "Bob", "Cat"}, []any{31, 42})
+	fmt.Printf("%+v %v", people, err)
+	// Output: [{Name:Ann Age:31} {Name:Bob Age:42}] <nil>
+}
+
+func TestZipIntoFieldCount(t *testing.T) {
+	var people []person
+	if err := ZipInto(&people, []any{"Ann"}, []any{31}, []any{true}); !errors.Is(err, ErrFieldCount) {
+		t.Error("expected ErrFieldCount, got: ", err)
+	}
+}
+
+func TestRepeatForever(t *testing.T) {
+	before := runningProducers()
+	counter := 0
+	for value := range Take(RepeatForever("x"), 4) {
+		if value != "x" {
+			t.Error("value not expected, got: ", value)
+		}
+		counter++
+	}
+	if counter != 4 {
+		t.Error("counter not expected number, got: ", counter, "expected :", 4)
+	}
+	ensureProducers(t, before)
+}
+
+func ExampleGroupByStreaming() {
+	words := Iter([]string{"apple", "avocado", "banana", "blueberry", "cherry", "apricot"})
+	for value := range GroupByStreaming(words, func(w any) byte { return w.(string)[0] }) {
+		group := value.(MapEntry[byte, Iterator])
+		fmt.Printf("%c:", group.Key)
+		for word := range group.Value {
+			fmt.Printf(" %v", word)
+		}
+		fmt.Println()
+	}
+	// Output:
+	// a: apple avocado
+	// b: banana blueberry
+	// c: cherry
+	// a: apricot
+}
+
+func TestGroupByStreamingSkipGroup(t *testing.T) {
+	numbers := Iter([]int{1, 1, 1, 2, 2, 3})
+	var keys, firsts []int
+	for value := range GroupByStreaming(numbers, func(n any) int { return n.(int) }) {
+		group := value.(MapEntry[int, Iterator])
+		keys = append(keys, group.Key)
+		firsts = append(firsts, Next(group.Value).(int))
+		Stop(group.Value)
+	}
+	if fmt.Sprint(keys) != "[1 2 3]" || fmt.Sprint(firsts) != "[1 2 3]" {
+		t.Error("groups not expected, got: ", keys, firsts)
+	}
+}
+
+func TestAllEqual(t *testing.T) {
+	if !AllEqual([]int{4, 4, 4}) || !AllEqual([]string{}) {
+		t.Error("expected all equal and empty slices to be all equal")
+	}
+	if AllEqual([]int{4, 4, 5, 4}) {
+		t.Error("expected a differing element to be caught")
+	}
+}
+
+func TestAllEqualIter(t *testing.T) {
+	if !AllEqualIter(Repeat("x", 3)) || !AllEqualIter(Iter([]int{})) {
+		t.Error("expected all equal and empty iterators to be all equal")
+	}
+	ch := RepeatSlice([]int{1, 2}, -1)
+	if AllEqualIter(ch) {
+		t.Error("expected a differing element to be caught")
+	}
+	ensureClosed(t, ch)
+}
+
+func TestPMapChunkedOrdered(t *testing.T) {
+	data := rand.Perm(1000)
+	double := func(chunk []int) []int {
+		result := make([]int, len(chunk))
+		for i, x := range chunk {
+			result[i] = x * 2
+		}
+		return result
+	}
+	counter := 0
+	for value := range PMapChunked(data, 7, 4, double) {
+		if value != data[counter]*2 {
+			t.Fatal("position", counter, "got: ", value, "expected :", data[counter]*2)
+		}
+		counter++
+	}
+	if counter != len(data) {
+		t.Error("counter not expected number, got: ", counter, "expected :", len(data))
+	}
+}
+
+func ExampleIndices() {
+	for value := range Indices([]int{3, 4, 7, 8, 10, 1}, func(n int) bool { return n%2 == 0 }) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1:3:4:
+}
+
+func ExampleSum() {
+	fmt.Println(Sum([]float64{1.5, 2, 3.5}))
+	// Output: 7
+}
+
+func ExampleSumColumns() {
+	fmt.Println(SumColumns([][]int{{1, 2}, {3, 4}, {5, 6}}))
+	// Output: [9 12] <nil>
+}
+
+func TestSumColumnsRagged(t *testing.T) {
+	if _, err := SumColumns([][]int{{1, 2}, {3}}); err != ErrUnequalLengths {
+		t.Error("expected ErrUnequalLengths, got: ", err)
+	}
+}
+
+func ExampleFillGaps() {
+	for value := range FillGaps([]int{1, 3, 7}, 1) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 1234567
+}
+
+func TestFillGapsUnsorted(t *testing.T) {
+	var last any
+	for value := range FillGaps([]int{1, 5, 3}, 1) {
+		last = value
+	}
+	if err, ok := last.(error); !ok || !errors.Is(err, ErrUnsorted) {
+		t.Error("expected ErrUnsorted, got: ", last)
+	}
+}
+
+func ExampleChainReflect() {
+	for value := range ChainReflect([]int{1, 2}, []string{"a", "b"}, [1]bool{true}) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 1:2:a:b:true:
+}
+
+func TestChainReflectNotSlice(t *testing.T) {
+	var values []any
+	for value := range ChainReflect([]int{1}, 2) {
+		values = append(values, value)
+	}
+	if len(values) != 2 || !errors.Is(values[1].(error), ErrUnexpectedType) {
+		t.Error("expected 1 then ErrUnexpectedType, got: ", values)
+	}
+}
+
+func TestRollingStdDev(t *testing.T) {
+	data := []float64{2, 4, 4, 4, 5, 5, 7, 9, 1.5, 3}
+	window := 4
+	counter := 0
+	for value := range RollingStdDev(data, window) {
+		current := data[counter : counter+window]
+		mean := Sum(current) / float64(window)
+		squares := 0.0
+		for _, x := range current {
+			squares += (x - mean) * (x - mean)
+		}
+		expected := math.Sqrt(squares / float64(window-1))
+		if math.Abs(value.(float64)-expected) > 1e-9 {
+			t.Error("window", counter, "got: ", value, "expected :", expected)
+		}
+		counter++
+	}
+	if counter != len(data)-window+1 {
+		t.Error("counter not expected number, got: ", counter, "expected :", len(data)-window+1)
+	}
+}
+
+func ExampleRollingStdDev() {
+	for value := range RollingStdDev([]int{1, 3, 5}, 1) {
+		fmt.Printf("%v:", value)
+	}
+	// Output: 0:0:0:
+}
+
+func ExampleSortedJoin() {
+	customers := []customer{{1, "Ann"}, {2, "Bob"}, {3, "Cat"}, {5, "Dan"}}
+	orders := []order{{1, "pen"}, {1, "pad"}, {3, "ink"}, {4, "cup"}, {5, "mug"}}
+	ch := SortedJoin(customers, orders, func(c customer) int { return c.ID }, func(o order) int { return o.CustomerID })
+	for value := range ch {
+		pair := value.(Pair[customer, order])
+		fmt.Printf("%v:%v ", pair.First.Name, pair.Second.Item)
+	}
+	// Output: Ann:pen Ann:pad Cat:ink Dan:mug
+}
+
+func TestSortedJoinNaN(t *testing.T) {
+	nan := math.NaN()
+	identity := func(x float64) float64 { return x }
+	var pairs []Pair[float64, float64]
+	for value := range Take(SortedJoin([]float64{nan, 1}, []float64{nan, nan, 1}, identity, identity), 10) {
+		pairs = append(pairs, value.(Pair[float64, float64]))
+	}
+	if len(pairs) != 3 || !math.IsNaN(pairs[0].Second) || !math.IsNaN(pairs[1].Second) || pairs[2].First != 1 {
+		t.Error("expected NaN keys to join with each other and 1 with 1, got: ", pairs)
+	}
+}
+
+func ExampleGroupUntil() {
+	batchOfThree := func(group []int, _ int) bool { return len(group) == 3 }
+	for value := range GroupUntil([]int{1, 2, 3, 4, 5, 6, 7}, batchOfThree) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 2 3][4 5 6][7]
+}
+
+func ExampleEnumerateRunes() {
+	for value := range EnumerateRunes("aé😀b") {
+		pair := value.(Pair[int, rune])
+		fmt.Printf("%d:%c ", pair.First, pair.Second)
+	}
+	// Output: 0:a 1:é 3:😀 7:b
+}
+
+func ExampleDistribute() {
+	fmt.Println(Distribute([]int{0, 1, 2, 3, 4, 5}, 3))
+	// Output: [[0 3] [1 4] [2 5]] <nil>
+}
+
+func TestDistributeInvalid(t *testing.T) {
+	if _, err := Distribute([]int{1}, 0); err != ErrInvalidSize {
+		t.Error("expected ErrInvalidSize, got: ", err)
+	}
+}
+
+func ExampleFilterPairs() {
+	names := []string{"Ann", "Bob", "Cat", "Dan"}
+	scores := []int{72, 45, 90, 51}
+	fmt.Println(FilterPairs(names, scores, func(_ string, score int) bool { return score >= 50 }))
+	// Output: [Ann Cat Dan] [72 90 51] <nil>
+}
+
+func TestFilterPairsMismatch(t *testing.T) {
+	if _, _, err := FilterPairs([]int{1, 2}, []int{1}, func(int, int) bool { return true }); err != ErrUnequalLengths {
+		t.Error("expected ErrUnequalLengths, got: ", err)
+	}
+}
+
+func TestNextTimeout(t *testing.T) {
+	ch := Iter([]int{1})
+	if value, ok, err := NextTimeout(ch, time.Second); value != 1 || !ok || err != nil {
+		t.Error("expected 1, got: ", value, ok, err)
+	}
+	if value, ok, err := NextTimeout(ch, time.Second); value != nil || ok || err != nil {
+		t.Error("expected a closed iterator, got: ", value, ok, err)
+	}
+	slow := make(Iterator)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		slow <- 2
+		close(slow)
+	}()
+	if _, ok, err := NextTimeout(slow, 10*time.Millisecond); ok || err != ErrTimeout {
+		t.Error("expected ErrTimeout, got: ", ok, err)
+	}
+	if value, ok, err := NextTimeout(slow, time.Second); value != 2 || !ok || err != nil {
+		t.Error("expected the value that was not taken by the timeout, got: ", value, ok, err)
+	}
+}
+
+func ExampleToMapMerge() {
+	items := []groupReduceItem{{"food", 5}, {"rent", 100}, {"food", 7}}
+	category := func(i groupReduceItem) string { return i.category }
+	amount := func(i groupReduceItem) int { return i.amount }
+	fmt.Println(ToMapMerge(items, category, amount, func(a, b int) int { return a + b }))
+	// Output: map[food:12 rent:100]
+}
+
+func ExampleToMapMerge_concat() {
+	words := []string{"apple", "banana", "avocado", "blueberry"}
+	first := func(w string) byte { return w[0] }
+	identity := func(w string) string { return w }
+	joined := ToMapMerge(words, first, identity, func(a, b string) string { return a + "," + b })
+	fmt.Println(joined['a'], joined['b'])
+	// Output: apple,avocado banana,blueberry
+}
+
+func ExampleCollect() {
+	fmt.Println(Collect[int](Iter([]int{1, 2, 3})))
+	// Output: [1 2 3]
+}
+
+func ExampleCollectLimit() {
+	fmt.Println(CollectLimit[int](Count(1, 1), 3))
+	// Output: [1 2 3] true
+}
+
+func TestCollectLimit(t *testing.T) {
+	for _, test := range []struct {
+		length   int
+		expected int
+		more     bool
+	}{{2, 2, false}, {3, 3, false}, {4, 3, true}} {
+		ch := Take(Count(0, 1), test.length)
+		result, more := CollectLimit[int](ch, 3)
+		if len(result) != test.expected || more != test.more {
+			t.Error("expected ", test.expected, " elements and ", test.more, " for length ", test.length, ", got: ", result, more)
+		}
+		ensureClosed(t, ch)
+	}
+	if result, more := CollectLimit[int](Iter([]int{1}), 0); len(result) != 0 || !more {
+		t.Error("expected nothing collected and more, got: ", result, more)
+	}
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		if result, more := CollectLimit[int](RepeatEach([]int{1, 2, 3}, 5), 2); !slices.Equal(result, []int{1, 1}) || !more {
+			t.Fatal("expected [1 1] and more, got: ", result, more)
+		}
+		CollectLimit[any](Chain([]int{1, 2, 3}, []int{4}), 1)
+		CollectLimit[[]any](Zip([]int{1, 2}, []int{3, 4}), 1)
+	}
+	ensureGoroutines(t, "CollectLimit", before)
+}
+
+func ExampleCollectResult() {
+	numbers, err := CollectResult[float64](IterJSONArray(strings.NewReader("[1, 2, oops]")))
+	fmt.Println(numbers, err != nil)
+	// Output: [1 2] true
+}
+
+func TestCollectResultError(t *testing.T) {
+	failure := errors.New("bad element")
+	ch := NewGenerator(func(yield func(any) bool) {
+		for i := 0; ; i++ {
+			value := any(i)
+			if i == 3 {
+				value = failure
+			}
+			if !yield(value) {
+				return
+			}
+		}
+	})
+	result, err := CollectResult[int](ch)
+	if !slices.Equal(result, []int{0, 1, 2}) || err != failure {
+		t.Error("expected [0 1 2] and the error, got: ", result, err)
+	}
+	ensureClosed(t, ch)
+	if _, err := CollectResult[int](Iter([]any{1, "x"})); !errors.Is(err, ErrUnexpectedType) {
+		t.Error("expected ErrUnexpectedType, got: ", err)
+	}
+}
+
+func ExampleCollectGroups() {
+	evens := NewPipeline(Iter([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})).Filter(func(x any) bool { return x.(int)%2 == 0 }).Iter()
+	groups := CollectGroups[int](evens, func(x any) bool { return x.(int) > 5 })
+	fmt.Println(groups[false], groups[true])
+	// Output: [2 4] [6 8 10]
+}
+
+func TestCollectGroupsSkipsOtherTypes(t *testing.T) {
+	groups := CollectGroups[string](Iter([]any{"ab", 1, "cd", "ae"}), func(x any) byte { return x.(string)[0] })
+	if len(groups) != 2 || !slices.Equal(groups['a'], []any{"ab", "ae"}) || !slices.Equal(groups['c'], []any{"cd"}) {
+		t.Error("expected groups a and c, got: ", groups)
+	}
+}
+
+func TestCollectCap(t *testing.T) {
+	result := CollectCap[string](Iter([]string{"a", "b"}), 10)
+	if len(result) != 2 || cap(result) != 10 {
+		t.Error("expected 2 elements with capacity 10, got: ", len(result), cap(result))
+	}
+}
+
+func ExampleWriteTo() {
+	var buf bytes.Buffer
+	n, err := WriteTo(Iter([]int{1, 22, 333}), &buf, func(x any) []byte { return []byte(fmt.Sprintf("<%v>", x)) })
+	fmt.Println(buf.String(), n, err)
+	// Output: <1><22><333> 12 <nil>
+}
+
+// failingWriter accepts limit bytes and then fails every write
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return 0, errors.New("writer full")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestWriteToError(t *testing.T) {
+	ch := Count(1, 1)
+	n, err := WriteTo(ch, &failingWriter{limit: 5}, func(x any) []byte { return []byte("ab") })
+	if err == nil || n != 4 {
+		t.Error("expected a write error after 4 bytes, got: ", n, err)
+	}
+	ensureClosed(t, ch)
+}
+
+func ExampleWriteCSV() {
+	var buf bytes.Buffer
+	people := Iter([]person{{"Ann", 30}, {"Bob, Jr", 25}})
+	err := WriteCSV(people, csv.NewWriter(&buf), func(x any) []string {
+		p := x.(person)
+		return []string{p.Name, strconv.Itoa(p.Age)}
+	})
+	fmt.Print(buf.String(), err)
+	// Output:
+	// Ann,30
+	// "Bob, Jr",25
+	// <nil>
+}
+
+func TestWriteCSVError(t *testing.T) {
+	w := csv.NewWriter(&failingWriter{limit: 3})
+	ch := Count(0, 1)
+	if err := WriteCSV(ch, w, func(x any) []string { return []string{strconv.Itoa(x.(int))} }); err == nil {
+		t.Error("expected the write error to be returned")
+	}
+	ensureClosed(t, ch)
+	var buf bytes.Buffer
+	failure := errors.New("bad record")
+	if err := WriteCSV(Iter([]any{1, failure}), csv.NewWriter(&buf), func(x any) []string { return []string{fmt.Sprint(x)} }); err != failure || buf.String() != "1\n" {
+		t.Error("expected the first row to be flushed before the error, got: ", err, buf.String())
+	}
+}
+
+func ExampleRollingMode() {
+	for value := range RollingMode([]string{"a", "b", "b", "a", "c", "c"}, 3) {
+		fmt.Printf("%v", value)
+	}
+	// Output: bbbc
+}
+
+func TestRollingMode(t *testing.T) {
+	naive := func(window []int) int {
+		mode, best := window[0], 0
+		for _, candidate := range window {
+			count := 0
+			for _, other := range window {
+				if other == candidate {
+					count++
+				}
+			}
+			if count > best {
+				mode, best = candidate, count
+			}
+		}
+		return mode
+	}
+	data := make([]int, 200)
+	for i := range data {
+		data[i] = rand.Intn(5)
+	}
+	for _, window := range []int{1, 4, 7, 200} {
+		i := 0
+		for value := range RollingMode(data, window) {
+			if expected := naive(data[i : i+window]); value != expected {
+				t.Fatal("window ", window, " at ", i, " expected ", expected, ", got: ", value)
+			}
+			i++
+		}
+		if i != len(data)-window+1 {
+			t.Error("expected ", len(data)-window+1, " windows, got: ", i)
+		}
+	}
+	if value := <-RollingMode([]int{1}, 0); value != ErrInvalidSize {
+		t.Error("expected ErrInvalidSize, got: ", value)
+	}
+}
+
+func ExampleDivergePoints() {
+	a := []int{1, 2, 3, 4, 5}
+	b := []int{1, 2, 0, 4, 5}
+	c := []int{1, 2, 3, 4, 9}
+	for value := range DivergePoints(a, b, c) {
+		point := value.(Pair[int, []any])
+		fmt.Println(point.First, point.Second)
+	}
+	// Output:
+	// 2 [3 0 3]
+	// 4 [5 5 9]
+}
+
+func TestDivergePoints(t *testing.T) {
+	if value := <-DivergePoints([]int{1, 2}, []int{1}); value != ErrUnequalLengths {
+		t.Error("expected ErrUnequalLengths, got: ", value)
+	}
+	for value := range DivergePoints([]string{"a"}, []string{"a"}) {
+		t.Error("expected no divergence, got: ", value)
+	}
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		for range Take(DivergePoints([]int{1, 2, 3}, []int{0, 0, 0}), 1) {
+		}
+	}
+	ensureGoroutines(t, "DivergePoints", before)
+}
+
+// fakeClock reports each element as arriving at the next offset of arrivals, or later if sleep has moved it past that
+type fakeClock struct {
+	current  time.Time
+	arrivals []time.Duration
+	slept    []time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	if len(c.arrivals) > 0 {
+		if arrival := time.Unix(0, 0).Add(c.arrivals[0]); arrival.After(c.current) {
+			c.current = arrival
+		}
+		c.arrivals = c.arrivals[1:]
+	}
+	return c.current
+}
+
+func (c *fakeClock) sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+	c.current = c.current.Add(d)
+}
+
+func TestTokenBucketDrop(t *testing.T) {
+	half := 500 * time.Millisecond
+	clock := &fakeClock{current: time.Unix(0, 0), arrivals: []time.Duration{0, 0, 0, 0, 0, 0, half, half, 2 * time.Second}}
+	var got []any
+	for value := range tokenBucket(Iter([]int{1, 2, 3, 4, 5, 6, 7, 8}), 2, 3, false, clock.now, clock.sleep) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{1, 2, 3, 6, 8}) {
+		t.Error("expected the burst of 3 then one element per half second, got: ", got)
+	}
+}
+
+func TestTokenBucketBlock(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(0, 0)}
+	var got []any
+	for value := range tokenBucket(Iter([]int{1, 2, 3, 4, 5}), 2, 2, true, clock.now, clock.sleep) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{1, 2, 3, 4, 5}) {
+		t.Error("expected every element to be forwarded, got: ", got)
+	}
+	half := 500 * time.Millisecond
+	if !slices.Equal(clock.slept, []time.Duration{half, half, half}) {
+		t.Error("expected a half second wait after the burst of 2, got: ", clock.slept)
+	}
+}
+
+func TestTokenBucketInvalid(t *testing.T) {
+	if value := <-TokenBucket(Iter([]int{1}), 0, 1, false); value != ErrInvalidSize {
+		t.Error("expected ErrInvalidSize, got: ", value)
+	}
+}
+
+func ExamplePartitionByKey() {
+	parity := func(x any) string {
+		if x.(int)%2 == 0 {
+			return "even"
+		}
+		return "odd"
+	}
+	outputs := PartitionByKey(Iter([]int{1, 2, 3, 4, 5, 6}), parity, []string{"even", "odd"})
+	var wg sync.WaitGroup
+	results := make(map[string][]any)
+	var mu sync.Mutex
+	for key, out := range outputs {
+		wg.Add(1)
+		go func(key string, out Iterator) {
+			defer wg.Done()
+			for value := range out {
+				mu.Lock()
+				results[key] = append(results[key], value)
+				mu.Unlock()
+			}
+		}(key, out)
+	}
+	wg.Wait()
+	fmt.Println(results["even"], results["odd"])
+	// Output: [2 4 6] [1 3 5]
+}
+
+func TestPartitionByKey(t *testing.T) {
+	before := runningProducers()
+	ch := Count(0, 1)
+	outputs := PartitionByKey(ch, func(x any) int { return x.(int) % 3 }, []int{0, 1})
+	var wg sync.WaitGroup
+	for key, out := range outputs {
+		wg.Add(1)
+		go func(key int, out Iterator) {
+			defer wg.Done()
+			for value := range Take(out, 5) {
+				if value.(int)%3 != key {
+					t.Error("expected key ", key, ", got: ", value)
+				}
+			}
+		}(key, out)
+	}
+	wg.Wait()
+	ensureClosed(t, ch)
+	ensureProducers(t, before)
+}
+
+func ExampleRunningDistinct() {
+	for value := range RunningDistinct([]string{"b", "a", "b", "c"}) {
+		fmt.Println(value)
+	}
+	// Output:
+	// [b]
+	// [b a]
+	// [b a]
+	// [b a c]
+}
+
+func TestRunningDistinctSnapshots(t *testing.T) {
+	var snapshots [][]int
+	for value := range RunningDistinct([]int{1, 2, 1, 3}) {
+		snapshots = append(snapshots, value.([]int))
+	}
+	snapshots[0][0] = 99
+	snapshots[1] = append(snapshots[1], 99)
+	expected := [][]int{{99}, {1, 2, 99}, {1, 2}, {1, 2, 3}}
+	for i := range expected {
+		if !slices.Equal(snapshots[i], expected[i]) {
+			t.Error("expected snapshot ", i, " to be ", expected[i], ", got: ", snapshots[i])
+		}
+	}
+}
+
+func ExampleSorted() {
+	for value := range Sorted([]int{3, 1, 2}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 123
+}
+
+func ExampleSortedBy() {
+	people := []person{{"Ann", 30}, {"Bob", 25}, {"Cat", 30}, {"Dan", 20}}
+	for value := range SortedBy(people, func(a, b person) bool { return a.Age < b.Age }) {
+		fmt.Printf("%v ", value.(person).Name)
+	}
+	// Output: Dan Bob Ann Cat
+}
+
+func TestSortedLeavesInput(t *testing.T) {
+	input := []string{"c", "a", "b"}
+	var got []any
+	for value := range Sorted(input) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{"a", "b", "c"}) {
+		t.Error("expected a b c, got: ", got)
+	}
+	if !slices.Equal(input, []string{"c", "a", "b"}) {
+		t.Error("expected the input to be left untouched, got: ", input)
+	}
+}
+
+func ExampleBuffered() {
+	p := Buffered(Iter([]string{"let", "x", "=", "1"}), 3)
+	ahead, ok := p.PeekN(3)
+	fmt.Println(ahead, ok)
+	for value, ok := p.Next(); ok; value, ok = p.Next() {
+		fmt.Printf("%v ", value)
+	}
+	// Output:
+	// [let x =] true
+	// let x = 1
+}
+
+func TestBufferedPeekN(t *testing.T) {
+	p := Buffered(Iter([]int{1, 2}), 3)
+	if ahead, ok := p.PeekN(3); ok || !slices.Equal(ahead, []any{1, 2}) {
+		t.Error("expected [1 2] false, got: ", ahead, ok)
+	}
+	if value, ok := p.Peek(); !ok || value != 1 {
+		t.Error("expected 1 true, got: ", value, ok)
+	}
+	for _, k := range []int{0, -1} {
+		if ahead, ok := p.PeekN(k); !ok || ahead == nil || len(ahead) != 0 {
+			t.Error("expected an empty peek for ", k, ", got: ", ahead, ok)
+		}
+	}
+	if ahead, ok := p.PeekN(4); ok || len(ahead) != 2 {
+		t.Error("expected peeking past the lookahead to fail, got: ", ahead, ok)
+	}
+	p.Next()
+	if ahead, ok := p.PeekN(1); !ok || !slices.Equal(ahead, []any{2}) {
+		t.Error("expected [2] true, got: ", ahead, ok)
+	}
+	p.Next()
+	if value, ok := p.Next(); ok {
+		t.Error("expected an exhausted Peekable, got: ", value)
+	}
+	if ahead, ok := p.PeekN(3); ok || len(ahead) != 0 {
+		t.Error("expected nothing left to peek, got: ", ahead)
+	}
+}
+
+func TestBufferedStop(t *testing.T) {
+	ch := Count(0, 1)
+	p := Buffered(ch, 2)
+	p.PeekN(2)
+	p.Stop()
+	ensureClosed(t, ch)
+}
+
+func ExampleZipReport() {
+	for value := range ZipReport([]int{1, 2}, []int{3, 4, 5}, []int{6}) {
+		row := value.(ZipRow[int])
+		fmt.Println(row.Index, row.Values, row.Exhausted)
+	}
+	// Output:
+	// 0 [1 3 6] []
+	// 1 [2 4 0] [2]
+	// 2 [0 5 0] [0 2]
+}
+
+func TestZipReportEmpty(t *testing.T) {
+	for value := range ZipReport[int]() {
+		t.Error("expected no rows, got: ", value)
+	}
+	for value := range ZipReport([]int{}, []int{}) {
+		t.Error("expected no rows, got: ", value)
+	}
+}
+
+func ExampleNewGenerator() {
+	rangeOf := func(start, stop, step int) Generator {
+		return func(yield func(any) bool) {
+			for i := start; i < stop; i += step {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	}
+	for value := range NewGenerator(rangeOf(0, 10, 3)) {
+		fmt.Printf("%v ", value)
+	}
+	// Output: 0 3 6 9
+}
+
+func TestNewGeneratorEarlyStop(t *testing.T) {
+	before := runningProducers()
+	var returned atomic.Bool
+	ch := NewGenerator(func(yield func(any) bool) {
+		defer returned.Store(true)
+		for i := 0; yield(i); i++ {
+		}
+	})
+	var got []any
+	for value := range Take(ch, 3) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{0, 1, 2}) {
+		t.Error("expected 0 1 2, got: ", got)
+	}
+	ensureClosed(t, ch)
+	ensureProducers(t, before)
+	if !returned.Load() {
+		t.Error("expected the generator function to return")
+	}
+}
+
+func ExampleClamp() {
+	for value := range Clamp(Iter([]int{-5, 0, 5, 10, 15}), 0, 10) {
+		fmt.Printf("%v ", value)
+	}
+	// Output: 0 0 5 10 10
+}
+
+func TestClampMixedTypes(t *testing.T) {
+	ch := Iter([]any{1, 2.5, 3})
+	var got []any
+	for value := range Clamp(ch, 0, 2) {
+		got = append(got, value)
+	}
+	if len(got) != 2 || got[0] != 1 {
+		t.Fatal("expected 1 then an error, got: ", got)
+	}
+	if err, ok := got[1].(error); !ok || !errors.Is(err, ErrUnexpectedType) {
+		t.Error("expected ErrUnexpectedType, got: ", got[1])
+	}
+	ensureClosed(t, ch)
+}
+
+func ExampleZipMasked() {
+	for value := range ZipMasked([]string{"a"}, []string{"x", "y", "z"}) {
+		row := value.(Pair[[]string, []bool])
+		fmt.Printf("%q %v\n", row.First, row.Second)
+	}
+	// Output:
+	// ["a" "x"] [true true]
+	// ["" "y"] [false true]
+	// ["" "z"] [false true]
+}
+
+func TestZipMaskedZeroValues(t *testing.T) {
+	rows := 0
+	for value := range ZipMasked([]int{0}, []int{0, 0, 0}) {
+		row := value.(Pair[[]int, []bool])
+		if row.Second[0] != (rows == 0) || !row.Second[1] {
+			t.Error("expected the mask to tell a real zero from a missing one at row ", rows, ", got: ", row.Second)
+		}
+		rows++
+	}
+	if rows != 3 {
+		t.Error("expected 3 rows, got: ", rows)
+	}
+}
+
+// treeNode is a small tree used by the FlattenTree tests
+type treeNode struct {
+	name     string
+	children []*treeNode
+}
+
+func sampleTree() *treeNode {
+	leaf := func(name string) *treeNode { return &treeNode{name: name} }
+	return &treeNode{name: "a", children: []*treeNode{
+		{name: "b", children: []*treeNode{leaf("d"), leaf("e")}},
+		{name: "c", children: []*treeNode{leaf("f")}},
+	}}
+}
+
+func ExampleFlattenTree() {
+	for value := range FlattenTree(sampleTree(), func(n *treeNode) []*treeNode { return n.children }) {
+		fmt.Printf("%v", value.(*treeNode).name)
+	}
+	// Output: abdecf
+}
+
+func ExampleFlattenTreeBFS() {
+	for value := range FlattenTreeBFS(sampleTree(), func(n *treeNode) []*treeNode { return n.children }) {
+		fmt.Printf("%v", value.(*treeNode).name)
+	}
+	// Output: abcdef
+}
+
+func TestFlattenTreeCycle(t *testing.T) {
+	before := runningProducers()
+	next := func(n int) []int { return []int{(n + 1) % 3} }
+	for _, walk := range []func(int, func(int) []int) Iterator{FlattenTree[int], FlattenTreeBFS[int]} {
+		var got []any
+		for value := range Take(walk(0, next), 5) {
+			got = append(got, value)
+		}
+		if !slices.Equal(got, []any{0, 1, 2, 0, 1}) {
+			t.Error("expected the cycle to repeat, got: ", got)
+		}
+	}
+	ensureProducers(t, before)
+}
+
+func TestThrottleByKey(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeClock{current: time.Unix(0, 0), arrivals: []time.Duration{0, 0, 5 * ms, 10 * ms, 12 * ms, 15 * ms, 20 * ms}}
+	events := []string{"a1", "b1", "a2", "a3", "b2", "b3", "a4"}
+	var got []any
+	for value := range throttleByKey(Iter(events), func(x any) byte { return x.(string)[0] }, 10*ms, clock.now) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{"a1", "b1", "a3", "b2", "a4"}) {
+		t.Error("expected each key to be paced independently, got: ", got)
+	}
+}
+
+func ExampleZipJSON() {
+	rows := Iter([][]any{{"Ann", 30}, {"Bob", 25}})
+	for value := range ZipJSON([]string{"name", "age"}, rows) {
+		encoded, _ := json.Marshal(value)
+		fmt.Println(string(encoded))
+	}
+	// Output:
+	// {"age":30,"name":"Ann"}
+	// {"age":25,"name":"Bob"}
+}
+
+func TestZipJSONErrors(t *testing.T) {
+	rows := Iter([]any{[]any{1, 2}, []any{1}, []any{3, 4}})
+	var got []any
+	for value := range ZipJSON([]string{"x", "y"}, rows) {
+		got = append(got, value)
+	}
+	if len(got) != 2 {
+		t.Fatal("expected one object then an error, got: ", got)
+	}
+	if err, ok := got[1].(error); !ok || !errors.Is(err, ErrUnequalLengths) {
+		t.Error("expected ErrUnequalLengths, got: ", got[1])
+	}
+	ensureClosed(t, rows)
+	if value := <-ZipJSON([]string{"x"}, Iter([]int{1})); !errors.Is(value.(error), ErrUnexpectedType) {
+		t.Error("expected ErrUnexpectedType, got: ", value)
+	}
+}
+
+func TestRandomSkip(t *testing.T) {
+	sample := func(seed int64, keepProb float64) []any {
+		var got []any
+		for value := range RandomSkip(Take(Count(0, 1), 1000), keepProb, rand.New(rand.NewSource(seed))) {
+			got = append(got, value)
+		}
+		return got
+	}
+	r := rand.New(rand.NewSource(7))
+	var expected []any
+	for i := 0; i < 1000; i++ {
+		if r.Float64() < 0.3 {
+			expected = append(expected, i)
+		}
+	}
+	got := sample(7, 0.3)
+	if !slices.Equal(got, expected) || !slices.Equal(sample(7, 0.3), got) {
+		t.Error("expected the same subset for the same seed, got: ", got)
+	}
+	if len(got) < 250 || len(got) > 350 {
+		t.Error("expected about 300 elements kept, got: ", len(got))
+	}
+	if len(sample(1, 0)) != 0 || len(sample(1, 1)) != 1000 {
+		t.Error("expected a probability of 0 to keep nothing and 1 to keep everything")
+	}
+	for _, keepProb := range []float64{-0.1, 1.5, math.NaN()} {
+		if value := <-RandomSkip(Iter([]int{1}), keepProb, r); !errors.Is(value.(error), ErrOutOfRange) {
+			t.Error("expected ErrOutOfRange for ", keepProb, ", got: ", value)
+		}
+	}
+}
+
+func ExampleDiff() {
+	for value := range Diff([]string{"a", "b", "c"}, []string{"a", "x", "b", "c"}, true) {
+		change := value.(Change[string])
+		fmt.Printf("%v %v %q %q\n", change.Index, change.Op, change.Old, change.New)
+	}
+	// Output:
+	// 1 changed "b" "x"
+	// 2 changed "c" "b"
+	// 3 added "" "c"
+}
+
+func TestDiff(t *testing.T) {
+	var ops []string
+	for value := range Diff([]int{1, 2, 3}, []int{1, 5}, false) {
+		ops = append(ops, value.(Change[int]).Op)
+	}
+	if !slices.Equal(ops, []string{OpEqual, OpChanged, OpRemoved}) {
+		t.Error("expected equal changed removed, got: ", ops)
+	}
+	for value := range Diff([]int{1}, []int{1}, true) {
+		t.Error("expected equal positions to be skipped, got: ", value)
+	}
+}
+
+func ExampleCountDistinct() {
+	fmt.Println(CountDistinct[string](Iter([]string{"a", "b", "a", "c", "b"})))
+	// Output: 3
+}
+
+func TestCountDistinctApprox(t *testing.T) {
+	for _, distinct := range []int{0, 10, 1000, 200000} {
+		values := make([]int, 2*distinct)
+		for i := range values {
+			values[i] = i % max(distinct, 1)
+		}
+		exact := CountDistinct[int](Iter(values))
+		approx := CountDistinctApprox(Iter(values))
+		if exact != distinct || math.Abs(float64(approx-exact)) > 0.03*float64(exact) {
+			t.Error("expected an estimate within 3% of ", exact, ", got: ", approx)
+		}
+	}
+	if approx := CountDistinctApprox(Iter([]any{1, "1", 1.0})); approx != 3 {
+		t.Error("expected values of different types to be told apart, got: ", approx)
+	}
+}
+
+func ExampleRepeatEach() {
+	for value := range RepeatEach([]string{"a", "b"}, 2) {
+		fmt.Printf("%v", value)
+	}
+	// Output: aabb
+}
+
+func TestRepeatEachZero(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		for value := range RepeatEach([]int{1, 2}, n) {
+			t.Error("expected nothing for ", n, ", got: ", value)
+		}
+	}
+}
+
+func ExampleExpandingWindow() {
+	for value := range ExpandingWindow([]int{1, 2, 3}) {
+		fmt.Println(value)
+	}
+	// Output:
+	// [1]
+	// [1 2]
+	// [1 2 3]
+}
+
+func TestExpandingWindowCopies(t *testing.T) {
+	input := []int{1, 2}
+	var windows [][]int
+	for value := range ExpandingWindow(input) {
+		windows = append(windows, value.([]int))
+	}
+	windows[0][0] = 9
+	windows[0] = append(windows[0], 9)
+	if input[0] != 1 || input[1] != 2 || !slices.Equal(windows[1], []int{1, 2}) {
+		t.Error("expected each window to be a copy, got: ", input, windows)
+	}
+}
+
+func ExampleMergeByPriority() {
+	sources := []Iterator{Iter([]int{1, 4, 7}), Iter([]int{2, 5}), Iter([]int{0, 3, 6, 9})}
+	for value := range MergeByPriority(sources, func(a, b any) bool { return a.(int) < b.(int) }) {
+		fmt.Printf("%v", value)
+	}
+	// Output: 012345679
+}
+
+func TestMergeByPriorityStops(t *testing.T) {
+	before := runningProducers()
+	evens, odds := Count(0, 2), Count(1, 2)
+	var got []any
+	for value := range Take(MergeByPriority([]Iterator{evens, odds}, func(a, b any) bool { return a.(int) < b.(int) }), 5) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{0, 1, 2, 3, 4}) {
+		t.Error("expected 0 to 4, got: ", got)
+	}
+	ensureClosed(t, evens)
+	ensureClosed(t, odds)
+	ensureProducers(t, before)
+	tied := MergeByPriority([]Iterator{Iter([]string{"a1", "b1"}), Iter([]string{"a2"})}, func(a, b any) bool { return a.(string)[0] < b.(string)[0] })
+	got = nil
+	for value := range tied {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{"a1", "a2", "b1"}) {
+		t.Error("expected ties to go to the earlier source, got: ", got)
+	}
+}
+
+func ExampleUnbatch() {
+	for value := range Unbatch(Iter([]any{[]int{1, 2}, []string{"a"}, []any{}, []any{3.5}})) {
+		fmt.Printf("%v ", value)
+	}
+	// Output: 1 2 a 3.5
+}
+
+func TestUnbatchRoundTrip(t *testing.T) {
+	input := []int{1, 2, 3, 4, 5, 6, 7}
+	var got []int
+	for value := range Unbatch(Segment(input, 3, false)) {
+		got = append(got, value.(int))
+	}
+	if !slices.Equal(got, input) {
+		t.Error("expected ", input, ", got: ", got)
+	}
+	ch := Iter([]any{[]int{1}, 2, []int{3}})
+	var values []any
+	for value := range Unbatch(ch) {
+		values = append(values, value)
+	}
+	if len(values) != 2 || !errors.Is(values[1].(error), ErrUnexpectedType) {
+		t.Error("expected 1 then ErrUnexpectedType, got: ", values)
+	}
+	ensureClosed(t, ch)
+}
+
+func ExampleCovariance() {
+	covariance, err := Covariance([]int{1, 2, 3, 4}, []int{2, 4, 6, 8})
+	fmt.Printf("%.4f %v\n", covariance, err)
+	// Output: 3.3333 <nil>
+}
+
+func TestCorrelation(t *testing.T) {
+	// Anscombe's quartet, each set has a correlation of about 0.816
+	x := []float64{10, 8, 13, 9, 11, 14, 6, 4, 12, 7, 5}
+	for _, y := range [][]float64{
+		{8.04, 6.95, 7.58, 8.81, 8.33, 9.96, 7.24, 4.26, 10.84, 4.82, 5.68},
+		{9.14, 8.14, 8.74, 8.77, 9.26, 8.10, 6.13, 3.10, 9.13, 7.26, 4.74},
+		{7.46, 6.77, 12.74, 7.11, 7.81, 8.84, 6.08, 5.39, 8.15, 6.42, 5.73},
+	} {
+		if r, err := Correlation(x, y); err != nil || math.Abs(r-0.816) > 0.001 {
+			t.Error("expected a correlation of 0.816, got: ", r, err)
+		}
+		if c, err := Covariance(x, y); err != nil || math.Abs(c-5.5) > 0.01 {
+			t.Error("expected a covariance of 5.5, got: ", c, err)
+		}
+	}
+	if r, _ := Correlation([]int{1, 2, 3}, []int{3, 2, 1}); math.Abs(r+1) > 1e-9 {
+		t.Error("expected a correlation of -1, got: ", r)
+	}
+	if _, err := Covariance([]int{1, 2}, []int{1}); err != ErrUnequalLengths {
+		t.Error("expected ErrUnequalLengths, got: ", err)
+	}
+	if _, err := Correlation([]int{1}, []int{1}); !errors.Is(err, ErrInvalidSize) {
+		t.Error("expected ErrInvalidSize, got: ", err)
+	}
+	if _, err := Correlation([]int{1, 1}, []int{1, 2}); err != ErrDivideByZero {
+		t.Error("expected ErrDivideByZero, got: ", err)
+	}
+}
+
+func ExampleDiff1() {
+	for value := range Diff1([]int{1, 3, 6, 10}) {
+		fmt.Printf("%v ", value)
+	}
+	// Output: 2 3 4
+}
+
+func TestDiffN(t *testing.T) {
+	var got []any
+	for value := range DiffN([]float64{1, 2, 4, 8, 16}, 2) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{3.0, 6.0, 12.0}) {
+		t.Error("expected 3 6 12, got: ", got)
+	}
+	for value := range Diff1([]int{5}) {
+		t.Error("expected nothing for a single element, got: ", value)
+	}
+	if value := <-DiffN([]int{1, 2}, 0); value != ErrInvalidSize {
+		t.Error("expected ErrInvalidSize, got: ", value)
+	}
+}
+
+func ExampleWindowView() {
+	for value := range WindowView([]int{1, 2, 3, 4}, 3) {
+		fmt.Println(value)
+	}
+	// Output:
+	// [1 2 3]
+	// [2 3 4]
+}
+
+func TestWindowView(t *testing.T) {
+	input := []int{1, 2, 3, 4, 5}
+	sums := []int{}
+	for value := range WindowView(input, 2) {
+		window := value.([]int)
+		sums = append(sums, window[0]+window[1])
+		if extended := append(window, 0); len(extended) != 3 {
+			t.Error("expected appending to a window to extend it, got: ", extended)
+		}
+	}
+	if !slices.Equal(sums, []int{3, 5, 7, 9}) || !slices.Equal(input, []int{1, 2, 3, 4, 5}) {
+		t.Error("expected sums 3 5 7 9 with the input untouched by append, got: ", sums, input)
+	}
+	if value := <-WindowView(input, 0); value != ErrInvalidSize {
+		t.Error("expected ErrInvalidSize, got: ", value)
+	}
+}
+
+func ExampleProductAny() {
+	for value := range ProductAny([]any{1, 2}, []any{"a", "b"}) {
+		fmt.Printf("%v", value)
+	}
+	// Output: [1 a][1 b][2 a][2 b]
+}
+
+func TestProductAnyEmpty(t *testing.T) {
+	for value := range ProductAny([]any{1, 2}, []any{}) {
+		t.Error("expected nothing when an input is empty, got: ", value)
+	}
+	var got []any
+	for value := range ProductAny([]any{true}, []any{1.5, "x"}, []any{nil}) {
+		got = append(got, value)
+	}
+	if len(got) != 2 || !slices.Equal(got[1].([]any), []any{true, "x", nil}) {
+		t.Error("expected 2 tuples ending [true x <nil>], got: ", got)
+	}
+}
+
+func ExampleMapReduce() {
+	square := func(x int) int { return x * x }
+	add := func(a, b int) int { return a + b }
+	fmt.Println(MapReduce([]int{1, 2, 3, 4}, square, add, 2, strconv.Itoa))
+	// Output: 30
+}
+
+func TestMapReduce(t *testing.T) {
+	numbers := rand.Perm(10000)
+	expected := 0
+	for _, n := range numbers {
+		expected += n
+	}
+	identity := func(x int) int { return x }
+	add := func(a, b int) int { return a + b }
+	for _, workers := range []int{0, 1, 3, 8, 20000} {
+		if total := MapReduce(numbers, identity, add, workers, identity); total != expected {
+			t.Error("expected ", expected, " with ", workers, " workers, got: ", total)
+		}
+	}
+	if total := MapReduce([]int{}, identity, add, 4, identity); total != 0 {
+		t.Error("expected 0 for no elements, got: ", total)
+	}
+
+	words := strings.Fields(strings.Repeat("the cat sat on the mat ", 50))
+	counts := MapReduce(words, func(w string) map[string]int { return map[string]int{w: 1} }, func(a, b map[string]int) map[string]int {
+		for word, n := range b {
+			a[word] += n
+		}
+		return a
+	}, 4, func(m map[string]int) map[string]int { return m })
+	baseline := make(map[string]int)
+	for _, word := range words {
+		baseline[word]++
+	}
+	if len(counts) != len(baseline) {
+		t.Error("expected counts to match ", baseline, ", got: ", counts)
+	}
+	for word, n := range baseline {
+		if counts[word] != n {
+			t.Error("expected ", n, " of ", word, ", got: ", counts[word])
+		}
+	}
+}
+
+func ExampleFillForward() {
+	readings := []float64{math.NaN(), 1.5, math.NaN(), math.NaN(), 2, math.NaN()}
+	for value := range FillForward(readings, math.IsNaN) {
+		fmt.Printf("%v ", value)
+	}
+	// Output: NaN 1.5 1.5 1.5 2 2
+}
+
+func TestFillForwardNothingPresent(t *testing.T) {
+	var got []any
+	for value := range FillForward([]string{"", ""}, func(s string) bool { return s == "" }) {
+		got = append(got, value)
+	}
+	if !slices.Equal(got, []any{"", ""}) {
+		t.Error("expected the missing values unchanged, got: ", got)
+	}
+}
+
+// ensureGoroutines fails t if the number of goroutines does not settle back to at most expected, unlike ensureProducers
+// it also catches a producer that has been deregistered but is still blocked, and only growth is counted as goroutines
+// left over from earlier tests may finish meanwhile
+func ensureGoroutines(t *testing.T, name string, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > expected {
+		if time.Now().After(deadline) {
+			t.Error(name, "left goroutines running, got:", runtime.NumGoroutine(), "expected:", expected)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStopSliceProducers(t *testing.T) {
+	constructors := map[string]func() Iterator{
+		"Chain":         func() Iterator { return Chain([]int{1, 2, 3}, []int{4}) },
+		"Zip":           func() Iterator { return Zip([]int{1, 2}, []int{3, 4}) },
+		"RepeatEach":    func() Iterator { return RepeatEach([]int{1, 2, 3}, 5) },
+		"Compress":      func() Iterator { return Compress([]int{1, 2, 3}, []bool{true, true, true}) },
+		"Pad":           func() Iterator { return Pad([]int{1}, 5, 0, false) },
+		"FilterMap":     func() Iterator { return FilterMap([]int{1, 2}, func(x int) (int, bool) { return x, true }) },
+		"Mask":          func() Iterator { return Mask([]int{1, 2}, []bool{true, false}, 0) },
+		"MovingAverage": func() Iterator { return MovingAverage([]int{1, 2, 3}, 1) },
+		"FillForward":   func() Iterator { return FillForward([]int{1, 0, 0}, func(x int) bool { return x == 0 }) },
+		"EnumerateZip2": func() Iterator { return EnumerateZip2([]int{1, 2}, []int{3, 4}) },
+		"Broadcast":     func() Iterator { return Broadcast(Iter([]int{1, 2}), 1)[0] },
+		"Clone":         func() Iterator { a, b := Clone(Iter([]int{1, 2})); Stop(b); return a },
+	}
+	for name, constructor := range constructors {
+		before := runtime.NumGoroutine()
+		for i := 0; i < 100; i++ {
+			for range Take(constructor(), 1) {
+			}
+		}
+		ensureGoroutines(t, name, before)
+	}
+}